@@ -168,6 +168,19 @@ type ACME struct {
 	CAServer    string
 	EntryPoint  string
 	storageLock sync.RWMutex
+	// called with the domains of each certificate obtained or renewed
+	certificateObtained func(domains []string, renewed bool)
+}
+
+// OnCertificate sets the function called with the domains of each certificate obtained or renewed.
+func (a *ACME) OnCertificate(certificateObtained func(domains []string, renewed bool)) {
+	a.certificateObtained = certificateObtained
+}
+
+func (a *ACME) notifyCertificate(domains []string, renewed bool) {
+	if a.certificateObtained != nil {
+		a.certificateObtained(domains, renewed)
+	}
 }
 
 // Domain holds a domain name with SANs
@@ -301,6 +314,7 @@ func (a *ACME) retrieveCertificates(client *acme.Client, account *Account) {
 				log.Errorf("Error Saving ACME account %+v: %s", account, err.Error())
 				continue
 			}
+			a.notifyCertificate(domains, false)
 		}
 	}
 	log.Infof("Retrieved ACME certificates")
@@ -335,6 +349,7 @@ func (a *ACME) renewCertificates(client *acme.Client, account *Account) error {
 			if err = a.saveAccount(account); err != nil {
 				return err
 			}
+			a.notifyCertificate(append([]string{certificateResource.Domains.Main}, certificateResource.Domains.SANs...), true)
 		}
 	}
 	return nil
@@ -369,6 +384,7 @@ func (a *ACME) loadCertificateOnDemand(client *acme.Client, Account *Account, cl
 	if err = a.saveAccount(Account); err != nil {
 		return nil, err
 	}
+	a.notifyCertificate([]string{clientHello.ServerName}, false)
 	return cert.tlsCert, nil
 }
 
@@ -0,0 +1,144 @@
+/*
+Copyright
+*/
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/oxy/roundrobin"
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/types"
+)
+
+// cachedBackend holds the handler built for a backend, kept across configuration reloads
+// as long as only the servers of the backend change.
+type cachedBackend struct {
+	configuration   types.Backend
+	passHostHeader  bool
+	retry           *Retry
+	handler         http.Handler
+	balancer        middlewares.ServerBalancer
+	outlierDetector *middlewares.OutlierDetector
+}
+
+// reusable returns true if the backend handler can be kept for the new configuration.
+func (backend *cachedBackend) reusable(configuration *types.Backend, passHostHeader bool, retry *Retry) bool {
+	if backend.passHostHeader != passHostHeader || !reflect.DeepEqual(backend.retry, retry) {
+		return false
+	}
+	// retries default to the number of servers
+	if retry != nil && retry.Attempts <= 0 && len(backend.configuration.Servers) != len(configuration.Servers) {
+		return false
+	}
+	previous := backend.configuration
+	previous.Servers = nil
+	current := *configuration
+	current.Servers = nil
+	return reflect.DeepEqual(previous, current)
+}
+
+// updateServers returns a function upserting the new and changed servers of the backend,
+// and removing the servers no longer in the configuration.
+func (backend *cachedBackend) updateServers(backendName string, servers map[string]types.Server) (func(), error) {
+	upserts := map[string]*url.URL{}
+	for serverName, server := range servers {
+		url, err := url.Parse(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := backend.configuration.Servers[serverName]; !ok || previous != server {
+			upserts[serverName] = url
+		}
+	}
+	removals := []*url.URL{}
+	for serverName, server := range backend.configuration.Servers {
+		if current, ok := servers[serverName]; ok && current.URL == server.URL {
+			continue
+		}
+		url, err := url.Parse(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		removals = append(removals, url)
+	}
+	if len(upserts) == 0 && len(removals) == 0 {
+		return nil, nil
+	}
+	return func() {
+		for _, url := range removals {
+			log.Debugf("Removing server %s from backend %s", url, backendName)
+			var err error
+			if backend.outlierDetector != nil {
+				err = backend.outlierDetector.RemoveServer(url)
+			} else {
+				err = backend.balancer.RemoveServer(url)
+			}
+			if err != nil {
+				log.Errorf("Error removing server %s from backend %s: %s", url, backendName, err)
+			}
+		}
+		for serverName, url := range upserts {
+			weight := servers[serverName].Weight
+			log.Debugf("Upserting server %s at %s with weight %d in backend %s", serverName, url, weight, backendName)
+			var err error
+			if backend.outlierDetector != nil {
+				err = backend.outlierDetector.UpsertServer(url, weight)
+			} else {
+				err = backend.balancer.UpsertServer(url, roundrobin.Weight(weight))
+			}
+			if err != nil {
+				log.Errorf("Error upserting server %s in backend %s: %s", url, backendName, err)
+			}
+		}
+	}, nil
+}
+
+// cachedFrontend holds the handler built for a frontend on top of its backends, kept across
+// configuration reloads as long as neither the frontend nor its backends change.
+type cachedFrontend struct {
+	configuration types.Frontend
+	backends      []http.Handler
+	handler       http.Handler
+	splitter      *middlewares.WeightedSplitter
+	mirror        *middlewares.Mirror
+	rateLimiter   *middlewares.RateLimiter
+	forwardAuth   *middlewares.ForwardAuth
+	errorPages    *middlewares.ErrorPages
+	responseCache *middlewares.ResponseCache
+	maintenance   *middlewares.Maintenance
+}
+
+// reusable returns true if the frontend handler can be kept for the new configuration.
+func (frontend *cachedFrontend) reusable(configuration *types.Frontend, backends []http.Handler) bool {
+	if len(frontend.backends) != len(backends) {
+		return false
+	}
+	for i, backend := range backends {
+		if frontend.backends[i] != backend {
+			return false
+		}
+	}
+	return reflect.DeepEqual(frontend.configuration, *configuration)
+}
+
+// frontendBackendNames returns the names of the backends a frontend sends traffic to.
+func frontendBackendNames(frontend *types.Frontend) []string {
+	backendNames := []string{frontend.Backend}
+	if len(frontend.Backends) > 0 {
+		backendNames = []string{}
+		for _, weightedBackend := range frontend.Backends {
+			backendNames = append(backendNames, weightedBackend.Name)
+		}
+	}
+	if frontend.Mirror != nil {
+		backendNames = append(backendNames, frontend.Mirror.Backend)
+	}
+	for _, errorPageName := range sortedErrorPageNames(frontend) {
+		backendNames = append(backendNames, frontend.Errors[errorPageName].Backend)
+	}
+	return backendNames
+}
@@ -97,7 +97,8 @@ func init() {
 	traefikCmd.PersistentFlags().StringP("graceTimeOut", "g", "10", "Timeout in seconds. Duration to give active requests a chance to finish during hot-reloads")
 	traefikCmd.PersistentFlags().String("accessLogsFile", "log/access.log", "Access logs file")
 	traefikCmd.PersistentFlags().String("traefikLogsFile", "log/traefik.log", "Traefik logs file")
-	traefikCmd.PersistentFlags().Var(&arguments.EntryPoints, "entryPoints", "Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key'")
+	traefikCmd.PersistentFlags().String("traefikLogsFormat", "text", "Traefik logs format: text or json")
+	traefikCmd.PersistentFlags().Var(&arguments.EntryPoints, "entryPoints", "Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key ForwardedHeaders.TrustedIPs:10.0.0.0/8'")
 	traefikCmd.PersistentFlags().Var(&arguments.DefaultEntryPoints, "defaultEntryPoints", "Entrypoints to be used by frontends that do not specify any entrypoint")
 	traefikCmd.PersistentFlags().StringP("logLevel", "l", "ERROR", "Log level")
 	traefikCmd.PersistentFlags().DurationVar(&arguments.ProvidersThrottleDuration, "providersThrottleDuration", time.Duration(2*time.Second), "Backends throttle duration: minimum duration between 2 events from providers before applying a new configuration. It avoids unnecessary reloads if multiples events are sent in a short amount of time.")
@@ -177,14 +178,19 @@ func init() {
 	_ = viper.BindPFlag("configFile", traefikCmd.PersistentFlags().Lookup("configFile"))
 	_ = viper.BindPFlag("graceTimeOut", traefikCmd.PersistentFlags().Lookup("graceTimeOut"))
 	_ = viper.BindPFlag("logLevel", traefikCmd.PersistentFlags().Lookup("logLevel"))
+	_ = viper.BindPFlag("traefikLogsFormat", traefikCmd.PersistentFlags().Lookup("traefikLogsFormat"))
 	// TODO: wait for this issue to be corrected: https://github.com/spf13/viper/issues/105
 	_ = viper.BindPFlag("providersThrottleDuration", traefikCmd.PersistentFlags().Lookup("providersThrottleDuration"))
 	_ = viper.BindPFlag("maxIdleConnsPerHost", traefikCmd.PersistentFlags().Lookup("maxIdleConnsPerHost"))
 	viper.SetDefault("providersThrottleDuration", time.Duration(2*time.Second))
 	viper.SetDefault("logLevel", "ERROR")
+	viper.SetDefault("traefikLogsFormat", "text")
 	viper.SetDefault("MaxIdleConnsPerHost", 200)
 }
 
+// traefikLog is the Traefik log file, if any, reopened for log rotations
+var traefikLog *logFile
+
 func run() {
 	fmtlog.SetFlags(fmtlog.Lshortfile | fmtlog.LstdFlags)
 
@@ -192,8 +198,6 @@ func run() {
 	globalConfiguration := LoadConfiguration()
 
 	http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost = globalConfiguration.MaxIdleConnsPerHost
-	loggerMiddleware := middlewares.NewLogger(globalConfiguration.AccessLogsFile)
-	defer loggerMiddleware.Close()
 
 	// logging
 	level, err := log.ParseLevel(strings.ToLower(globalConfiguration.LogLevel))
@@ -202,21 +206,30 @@ func run() {
 	}
 	log.SetLevel(level)
 
+	var formatter log.Formatter
+	switch strings.ToLower(globalConfiguration.TraefikLogsFormat) {
+	case "", "text":
+		formatter = &log.TextFormatter{DisableColors: len(globalConfiguration.TraefikLogsFile) > 0, FullTimestamp: true, DisableSorting: true}
+	case "json":
+		formatter = &log.JSONFormatter{}
+	default:
+		log.Fatalf("Unknown Traefik logs format %s", globalConfiguration.TraefikLogsFormat)
+	}
+	log.SetFormatter(formatter)
+
 	if len(globalConfiguration.TraefikLogsFile) > 0 {
-		fi, err := os.OpenFile(globalConfiguration.TraefikLogsFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-		defer func() {
-			if err := fi.Close(); err != nil {
-				log.Error("Error closinf file", err)
-			}
-		}()
+		fi, err := openLogFile(globalConfiguration.TraefikLogsFile)
 		if err != nil {
 			log.Fatal("Error opening file", err)
 		} else {
+			defer func() {
+				if err := fi.Close(); err != nil {
+					log.Error("Error closinf file", err)
+				}
+			}()
+			traefikLog = fi
 			log.SetOutput(fi)
-			log.SetFormatter(&log.TextFormatter{DisableColors: true, FullTimestamp: true, DisableSorting: true})
 		}
-	} else {
-		log.SetFormatter(&log.TextFormatter{FullTimestamp: true, DisableSorting: true})
 	}
 	jsonConf, _ := json.Marshal(globalConfiguration)
 	log.Debugf("Global configuration loaded %s", string(jsonConf))
@@ -21,6 +21,7 @@ type GlobalConfiguration struct {
 	GraceTimeOut              int64
 	AccessLogsFile            string
 	TraefikLogsFile           string
+	TraefikLogsFormat         string
 	LogLevel                  string
 	EntryPoints               EntryPoints
 	ACME                      *acme.ACME
@@ -28,6 +29,12 @@ type GlobalConfiguration struct {
 	ProvidersThrottleDuration time.Duration
 	MaxIdleConnsPerHost       int
 	Retry                     *Retry
+	AccessLog                 *AccessLog
+	RequestID                 *RequestID
+	Tracing                   *Tracing
+	Metrics                   *Metrics
+	NotFoundPage              string
+	ConfigurationHistorySize  int
 	Docker                    *provider.Docker
 	File                      *provider.File
 	Web                       *WebProvider
@@ -81,7 +88,7 @@ func (ep *EntryPoints) String() string {
 // Set's argument is a string to be parsed to set the flag.
 // It's a comma-separated list, so we split it.
 func (ep *EntryPoints) Set(value string) error {
-	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?")
+	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?\\s*(?:ForwardedHeaders.Insecure:(?P<ForwardedHeadersInsecure>\\S*))?\\s*(?:ForwardedHeaders.TrustedIPs:(?P<ForwardedHeadersTrustedIPs>\\S*))?")
 	match := regex.FindAllStringSubmatch(value, -1)
 	if match == nil {
 		return errors.New("Bad EntryPoints format: " + value)
@@ -111,11 +118,21 @@ func (ep *EntryPoints) Set(value string) error {
 			Replacement: result["RedirectReplacement"],
 		}
 	}
+	var forwardedHeaders *ForwardedHeaders
+	if len(result["ForwardedHeadersInsecure"]) > 0 || len(result["ForwardedHeadersTrustedIPs"]) > 0 {
+		forwardedHeaders = &ForwardedHeaders{
+			Insecure: strings.EqualFold(result["ForwardedHeadersInsecure"], "true"),
+		}
+		if len(result["ForwardedHeadersTrustedIPs"]) > 0 {
+			forwardedHeaders.TrustedIPs = strings.Split(result["ForwardedHeadersTrustedIPs"], ",")
+		}
+	}
 
 	(*ep)[result["Name"]] = &EntryPoint{
-		Address:  result["Address"],
-		TLS:      tls,
-		Redirect: redirect,
+		Address:          result["Address"],
+		TLS:              tls,
+		Redirect:         redirect,
+		ForwardedHeaders: forwardedHeaders,
 	}
 
 	return nil
@@ -128,10 +145,21 @@ func (ep *EntryPoints) Type() string {
 
 // EntryPoint holds an entry point configuration of the reverse proxy (ip, port, TLS...)
 type EntryPoint struct {
-	Network  string
-	Address  string
-	TLS      *TLS
-	Redirect *Redirect
+	Network          string
+	Address          string
+	TLS              *TLS
+	Redirect         *Redirect
+	Auth             *types.Auth
+	ForwardAuth      *types.ForwardAuth
+	ForwardedHeaders *ForwardedHeaders
+}
+
+// ForwardedHeaders configures which proxies in front of an entry point are trusted: the X-Forwarded-*
+// and Forwarded headers of their requests are kept and give the client IP, while they are removed from
+// the requests of the other sources. Without this configuration, all the proxies are trusted.
+type ForwardedHeaders struct {
+	Insecure   bool
+	TrustedIPs []string
 }
 
 // Redirect configures a redirection of an entry point to another, or to an URL
@@ -190,6 +218,81 @@ type Retry struct {
 	MaxMem   int64
 }
 
+// AccessLog contains the access log format, filters and buffering config
+type AccessLog struct {
+	Format          string
+	Fields          []string
+	RequestHeaders  []string
+	ResponseHeaders []string
+	RedactedHeaders []string
+	KeepQueryString bool
+	StatusCodes     []string
+	MinDuration     time.Duration
+	Frontends       []string
+	Sampling        float64
+	BufferSize      int
+	FlushInterval   time.Duration
+}
+
+// RequestID contains request ID config
+type RequestID struct {
+	Header         string
+	Format         string
+	IgnoreIncoming bool
+}
+
+// Tracing contains tracing config
+type Tracing struct {
+	Backend      string
+	ServiceName  string
+	SamplingRate float64
+	Jaeger       *JaegerTracing
+	Zipkin       *ZipkinTracing
+}
+
+// JaegerTracing contains Jaeger tracer config
+type JaegerTracing struct {
+	LocalAgentHostPort string
+	Propagation        string
+}
+
+// ZipkinTracing contains Zipkin tracer config
+type ZipkinTracing struct {
+	HTTPEndpoint string
+}
+
+// Metrics contains metrics config
+type Metrics struct {
+	Prometheus *Prometheus
+	StatsD     *StatsD
+	DogStatsD  *StatsD
+	InfluxDB   *InfluxDB
+}
+
+// Prometheus contains Prometheus metrics config
+type Prometheus struct {
+	Buckets []float64
+}
+
+// StatsD contains StatsD and DogStatsD metrics config
+type StatsD struct {
+	Address      string
+	Prefix       string
+	PushInterval time.Duration
+}
+
+// InfluxDB contains InfluxDB metrics config
+type InfluxDB struct {
+	Protocol        string
+	Address         string
+	Database        string
+	RetentionPolicy string
+	Username        string
+	Password        string
+	Prefix          string
+	PushInterval    time.Duration
+}
+
 // NewGlobalConfiguration returns a GlobalConfiguration with default values.
 func NewGlobalConfiguration() *GlobalConfiguration {
 	return new(GlobalConfiguration)
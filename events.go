@@ -0,0 +1,194 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/types"
+)
+
+// Types of the events streamed by /api/events
+const (
+	eventConfigurationReceived  = "configuration_received"
+	eventConfigurationThrottled = "configuration_throttled"
+	eventConfigurationApplied   = "configuration_applied"
+	eventConfigurationRejected  = "configuration_rejected"
+	eventCertificateObtained    = "certificate_obtained"
+	eventCertificateRenewed     = "certificate_renewed"
+	eventServerStateChanged     = "server_state_changed"
+)
+
+// event is something that happened to the configuration of Traefik, streamed by /api/events.
+type event struct {
+	Type     string             `json:"type"`
+	Time     time.Time          `json:"time"`
+	Provider string             `json:"provider,omitempty"`
+	Diff     *configurationDiff `json:"diff,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Domains  []string           `json:"domains,omitempty"`
+	Backend  string             `json:"backend,omitempty"`
+	Server   string             `json:"server,omitempty"`
+	Up       *bool              `json:"up,omitempty"`
+}
+
+// configurationDiff summarizes the differences between two configurations of a provider.
+type configurationDiff struct {
+	AddedFrontends   []string                        `json:"addedFrontends,omitempty"`
+	ChangedFrontends []string                        `json:"changedFrontends,omitempty"`
+	RemovedFrontends []string                        `json:"removedFrontends,omitempty"`
+	AddedBackends    []string                        `json:"addedBackends,omitempty"`
+	ChangedBackends  []string                        `json:"changedBackends,omitempty"`
+	RemovedBackends  []string                        `json:"removedBackends,omitempty"`
+	FrontendChanges  map[string]*configurationChange `json:"frontendChanges,omitempty"`
+	BackendChanges   map[string]*configurationChange `json:"backendChanges,omitempty"`
+}
+
+// configurationChange details what changed in a frontend or a backend: its settings, named like in
+// the configuration, and the servers of a backend.
+type configurationChange struct {
+	Settings       []string `json:"settings,omitempty"`
+	AddedServers   []string `json:"addedServers,omitempty"`
+	ChangedServers []string `json:"changedServers,omitempty"`
+	RemovedServers []string `json:"removedServers,omitempty"`
+}
+
+// diffConfigurations returns the frontends and backends added, changed and removed from previous
+// to current, with what changed in them, or nil if there is no current configuration, as it is
+// not applied.
+func diffConfigurations(previous, current *types.Configuration) *configurationDiff {
+	if current == nil {
+		return nil
+	}
+	if previous == nil {
+		previous = &types.Configuration{}
+	}
+	diff := &configurationDiff{}
+	for name, frontend := range current.Frontends {
+		if previousFrontend, ok := previous.Frontends[name]; !ok {
+			diff.AddedFrontends = append(diff.AddedFrontends, name)
+		} else if !reflect.DeepEqual(previousFrontend, frontend) {
+			diff.ChangedFrontends = append(diff.ChangedFrontends, name)
+			if diff.FrontendChanges == nil {
+				diff.FrontendChanges = map[string]*configurationChange{}
+			}
+			diff.FrontendChanges[name] = &configurationChange{Settings: changedSettings(previousFrontend, frontend)}
+		}
+	}
+	for name := range previous.Frontends {
+		if _, ok := current.Frontends[name]; !ok {
+			diff.RemovedFrontends = append(diff.RemovedFrontends, name)
+		}
+	}
+	for name, backend := range current.Backends {
+		if previousBackend, ok := previous.Backends[name]; !ok {
+			diff.AddedBackends = append(diff.AddedBackends, name)
+		} else if !reflect.DeepEqual(previousBackend, backend) {
+			diff.ChangedBackends = append(diff.ChangedBackends, name)
+			if diff.BackendChanges == nil {
+				diff.BackendChanges = map[string]*configurationChange{}
+			}
+			diff.BackendChanges[name] = diffBackends(previousBackend, backend)
+		}
+	}
+	for name := range previous.Backends {
+		if _, ok := current.Backends[name]; !ok {
+			diff.RemovedBackends = append(diff.RemovedBackends, name)
+		}
+	}
+	for _, names := range [][]string{diff.AddedFrontends, diff.ChangedFrontends, diff.RemovedFrontends, diff.AddedBackends, diff.ChangedBackends, diff.RemovedBackends} {
+		sort.Strings(names)
+	}
+	return diff
+}
+
+// diffBackends returns the settings and the servers changed from previous to current.
+func diffBackends(previous, current *types.Backend) *configurationChange {
+	change := &configurationChange{Settings: changedSettings(previous, current)}
+	if previous == nil {
+		previous = &types.Backend{}
+	}
+	if current == nil {
+		current = &types.Backend{}
+	}
+	for name, server := range current.Servers {
+		if previousServer, ok := previous.Servers[name]; !ok {
+			change.AddedServers = append(change.AddedServers, name)
+		} else if previousServer != server {
+			change.ChangedServers = append(change.ChangedServers, name)
+		}
+	}
+	for name := range previous.Servers {
+		if _, ok := current.Servers[name]; !ok {
+			change.RemovedServers = append(change.RemovedServers, name)
+		}
+	}
+	for _, names := range [][]string{change.AddedServers, change.ChangedServers, change.RemovedServers} {
+		sort.Strings(names)
+	}
+	return change
+}
+
+// changedSettings returns the JSON names of the fields which differ between two frontends or two
+// backends, except the servers of the backends.
+func changedSettings(previous, current interface{}) []string {
+	previousValue, currentValue := reflect.ValueOf(previous), reflect.ValueOf(current)
+	if previousValue.IsNil() || currentValue.IsNil() {
+		return nil
+	}
+	previousValue, currentValue = previousValue.Elem(), currentValue.Elem()
+	settings := []string{}
+	for i := 0; i < currentValue.NumField(); i++ {
+		name := strings.Split(currentValue.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "servers" {
+			continue
+		}
+		if !reflect.DeepEqual(previousValue.Field(i).Interface(), currentValue.Field(i).Interface()) {
+			settings = append(settings, name)
+		}
+	}
+	return settings
+}
+
+// eventBroker sends the published events to all the subscribers.
+type eventBroker struct {
+	mutex       sync.Mutex
+	subscribers map[chan event]bool
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: map[chan event]bool{}}
+}
+
+// subscribe returns a channel receiving the events published from now on, until unsubscribe is called.
+func (eb *eventBroker) subscribe() chan event {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+	events := make(chan event, 32)
+	eb.subscribers[events] = true
+	return events
+}
+
+func (eb *eventBroker) unsubscribe(events chan event) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+	delete(eb.subscribers, events)
+}
+
+// publish sends e to the subscribers without waiting for them: the subscribers too slow to
+// receive it miss it.
+func (eb *eventBroker) publish(e event) {
+	e.Time = time.Now()
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+	for events := range eb.subscribers {
+		select {
+		case events <- e:
+		default:
+			log.Warnf("Dropping %s event for a slow subscriber", e.Type)
+		}
+	}
+}
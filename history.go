@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/types"
+)
+
+// configurationVersion is a configuration of a provider, as applied at some time.
+type configurationVersion struct {
+	Version       int                  `json:"version"`
+	Time          time.Time            `json:"time"`
+	Diff          *configurationDiff   `json:"diff"`
+	Configuration *types.Configuration `json:"configuration,omitempty"`
+}
+
+// configurationHistory keeps the last versions of the configurations applied, by provider.
+type configurationHistory struct {
+	mutex    sync.RWMutex
+	size     int
+	versions map[string][]*configurationVersion
+}
+
+func newConfigurationHistory(size int) *configurationHistory {
+	return &configurationHistory{size: size, versions: map[string][]*configurationVersion{}}
+}
+
+// add records a copy of the configuration just applied for the provider, with its diff from the
+// previous one, forgetting the oldest version of the provider if there are too many.
+func (ch *configurationHistory) add(providerName string, configuration *types.Configuration, diff *configurationDiff) {
+	configurationCopy, err := copyConfiguration(configuration)
+	if err != nil {
+		log.Errorf("Error recording the configuration of %s in the history: %s", providerName, err)
+		return
+	}
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	versions := ch.versions[providerName]
+	version := &configurationVersion{Version: 1, Time: time.Now(), Diff: diff, Configuration: configurationCopy}
+	if len(versions) > 0 {
+		version.Version = versions[len(versions)-1].Version + 1
+	}
+	versions = append(versions, version)
+	if len(versions) > ch.size {
+		versions = append([]*configurationVersion(nil), versions[len(versions)-ch.size:]...)
+	}
+	ch.versions[providerName] = versions
+}
+
+// providers returns the names of the providers with a history, sorted.
+func (ch *configurationHistory) providers() []string {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	names := []string{}
+	for providerName := range ch.versions {
+		names = append(names, providerName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summaries returns the versions of the provider without their configurations, from the oldest.
+func (ch *configurationHistory) summaries(providerName string) []configurationVersion {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	summaries := []configurationVersion{}
+	for _, version := range ch.versions[providerName] {
+		summary := *version
+		summary.Configuration = nil
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// get returns a version of the configuration of the provider, if it is still in the history.
+func (ch *configurationHistory) get(providerName string, version int) (*configurationVersion, bool) {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	for _, v := range ch.versions[providerName] {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -60,7 +60,8 @@ func (s *AccessLogSuite) TestAccessLog(c *check.C) {
 	_, err = http.Get("http://127.0.0.1:8000/test2")
 	c.Assert(err, checker.IsNil)
 
-	// Verify access.log output as expected
+	// Verify access.log output as expected, once the lines are flushed
+	time.Sleep(100 * time.Millisecond)
 	accessLog, err := ioutil.ReadFile("access.log")
 	c.Assert(err, checker.IsNil)
 	lines := strings.Split(string(accessLog), "\n")
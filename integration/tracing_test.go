@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-check/check"
+
+	checker "github.com/vdemeester/shakers"
+)
+
+// TracingSuite
+type TracingSuite struct{ BaseSuite }
+
+// zipkinCollector records the spans posted to a Zipkin collector.
+type zipkinCollector struct {
+	mutex        sync.Mutex
+	contentTypes []string
+	spans        string
+}
+
+func (z *zipkinCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	if r.Method == "POST" && r.URL.Path == "/api/v1/spans" {
+		z.contentTypes = append(z.contentTypes, r.Header.Get("Content-Type"))
+		z.spans += string(body)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// received returns the content types and the spans posted, once they contain all the expected
+// strings or the timeout expires.
+func (z *zipkinCollector) received(timeout time.Duration, expected ...string) ([]string, string) {
+	deadline := time.Now().Add(timeout)
+	for {
+		z.mutex.Lock()
+		contentTypes, spans := z.contentTypes, z.spans
+		z.mutex.Unlock()
+		found := true
+		for _, s := range expected {
+			found = found && strings.Contains(spans, s)
+		}
+		if found || time.Now().After(deadline) {
+			return contentTypes, spans
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (s *TracingSuite) TestZipkin(c *check.C) {
+	os.Remove("traefik.log")
+
+	collector := &zipkinCollector{}
+	ts := startTracingServer(9411, collector)
+	defer ts.Close()
+	var backendHeaders http.Header
+	backend := startTracingServer(8081, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHeaders = r.Header
+	}))
+	defer backend.Close()
+
+	// Start Traefik
+	cmd := exec.Command(traefikBinary, "--configFile=fixtures/tracing_zipkin.toml")
+	err := cmd.Start()
+	c.Assert(err, checker.IsNil)
+	defer cmd.Process.Kill()
+	defer os.Remove("traefik.log")
+
+	time.Sleep(500 * time.Millisecond)
+
+	// The request carries the span of the client in the B3 headers
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8000/test", nil)
+	c.Assert(err, checker.IsNil)
+	req.Header.Set("X-B3-TraceId", "463ac35c9f6413ad")
+	req.Header.Set("X-B3-SpanId", "72485a3953bb6124")
+	req.Header.Set("X-B3-Sampled", "1")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, checker.IsNil)
+	c.Assert(resp.StatusCode, checker.Equals, 200)
+
+	// The backend gets the trace of the client, with the span of the forward call
+	c.Assert(backendHeaders["X-B3-Traceid"], checker.DeepEquals, []string{"463ac35c9f6413ad"})
+	c.Assert(backendHeaders["X-B3-Spanid"], checker.HasLen, 1)
+	c.Assert(backendHeaders.Get("X-B3-Spanid"), checker.Not(checker.Equals), "72485a3953bb6124")
+	c.Assert(backendHeaders["X-B3-Parentspanid"], checker.HasLen, 1)
+
+	// The spans are reported to the collector in the Thrift encoding of Zipkin
+	contentTypes, spans := collector.received(5*time.Second, "frontend frontend1", "forward backend1")
+	c.Assert(len(contentTypes) > 0, checker.True)
+	for _, contentType := range contentTypes {
+		c.Assert(contentType, checker.Equals, "application/x-thrift")
+	}
+	c.Assert(spans, checker.Contains, "traefik")
+	c.Assert(spans, checker.Contains, "frontend frontend1")
+	c.Assert(spans, checker.Contains, "forward backend1")
+
+	// Verify no other Traefik problems
+	traefikLog, err := ioutil.ReadFile("traefik.log")
+	c.Assert(err, checker.IsNil)
+	if len(traefikLog) > 0 {
+		fmt.Printf("%s\n", string(traefikLog))
+		c.Assert(len(traefikLog), checker.Equals, 0)
+	}
+}
+
+func startTracingServer(port int, handler http.Handler) (ts *httptest.Server) {
+	if listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err != nil {
+		panic(err)
+	} else {
+		ts = &httptest.Server{
+			Listener: listener,
+			Config:   &http.Server{Handler: handler},
+		}
+		ts.Start()
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// logFile is a log file which can be reopened once moved by a log rotation.
+type logFile struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func openLogFile(path string) (*logFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &logFile{file: file}, nil
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Write(p)
+}
+
+// Reopen closes the file and opens it again at the same path.
+func (f *logFile) Reopen() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.OpenFile(f.file.Name(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file = file
+	return nil
+}
+
+// Close closes the file.
+func (f *logFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/middlewares"
+)
+
+// newMetrics creates the metrics of the configured backends, and the handler exposing them in the
+// Prometheus format if Prometheus is configured.
+func newMetrics(configuration *Metrics) (*middlewares.Metrics, http.Handler, error) {
+	metricsList := []*middlewares.Metrics{}
+	var handler http.Handler
+	if configuration.Prometheus != nil {
+		buckets := []float64{0.1, 0.3, 1.2, 5}
+		if len(configuration.Prometheus.Buckets) > 0 {
+			buckets = append([]float64(nil), configuration.Prometheus.Buckets...)
+			sort.Float64s(buckets)
+		}
+		log.Infof("Exposing Prometheus metrics with request duration buckets %v", buckets)
+		prometheusMetrics, prometheusHandler := middlewares.NewPrometheusMetrics(buckets)
+		metricsList = append(metricsList, prometheusMetrics)
+		handler = prometheusHandler
+	}
+	if configuration.StatsD != nil {
+		address, prefix, pushInterval := statsDOptions(configuration.StatsD)
+		log.Infof("Pushing metrics to StatsD at %s every %s", address, pushInterval)
+		metricsList = append(metricsList, middlewares.NewStatsDMetrics(address, prefix, pushInterval))
+	}
+	if configuration.DogStatsD != nil {
+		address, prefix, pushInterval := statsDOptions(configuration.DogStatsD)
+		log.Infof("Pushing metrics to DogStatsD at %s every %s", address, pushInterval)
+		metricsList = append(metricsList, middlewares.NewDogStatsDMetrics(address, prefix, pushInterval))
+	}
+	if configuration.InfluxDB != nil {
+		options := middlewares.InfluxDBOptions{
+			Protocol:        configuration.InfluxDB.Protocol,
+			Address:         configuration.InfluxDB.Address,
+			Database:        configuration.InfluxDB.Database,
+			RetentionPolicy: configuration.InfluxDB.RetentionPolicy,
+			Username:        configuration.InfluxDB.Username,
+			Password:        configuration.InfluxDB.Password,
+			Prefix:          configuration.InfluxDB.Prefix,
+			PushInterval:    configuration.InfluxDB.PushInterval,
+		}
+		if len(options.Protocol) == 0 {
+			options.Protocol = "udp"
+		}
+		if len(options.Address) == 0 {
+			if options.Protocol == "http" {
+				options.Address = "http://localhost:8086"
+			} else {
+				options.Address = "localhost:8089"
+			}
+		}
+		if len(options.Database) == 0 {
+			options.Database = "traefik"
+		}
+		if len(options.Prefix) == 0 {
+			options.Prefix = "traefik."
+		}
+		if options.PushInterval <= 0 {
+			options.PushInterval = 10 * time.Second
+		}
+		influxDBMetrics, err := middlewares.NewInfluxDBMetrics(options)
+		if err != nil {
+			for _, m := range metricsList {
+				m.Close()
+			}
+			return nil, nil, err
+		}
+		log.Infof("Pushing metrics to InfluxDB over %s at %s every %s", options.Protocol, options.Address, options.PushInterval)
+		metricsList = append(metricsList, influxDBMetrics)
+	}
+	switch len(metricsList) {
+	case 0:
+		return nil, nil, errors.New("No metrics backend configured")
+	case 1:
+		return metricsList[0], handler, nil
+	default:
+		return middlewares.NewMultiMetrics(metricsList...), handler, nil
+	}
+}
+
+// statsDOptions returns the address, prefix and push interval of a StatsD configuration, with their defaults.
+func statsDOptions(statsD *StatsD) (string, string, time.Duration) {
+	address := statsD.Address
+	if len(address) == 0 {
+		address = "localhost:8125"
+	}
+	prefix := statsD.Prefix
+	if len(prefix) == 0 {
+		prefix = "traefik."
+	}
+	pushInterval := statsD.PushInterval
+	if pushInterval <= 0 {
+		pushInterval = 10 * time.Second
+	}
+	return address, prefix, pushInterval
+}
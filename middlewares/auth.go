@@ -0,0 +1,416 @@
+package middlewares
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	usersFileCheckPeriod = time.Second
+	digestNonceLifetime  = 5 * time.Minute
+	// maxDigestNonces bounds the nonces created during a digestNonceLifetime: past it, the oldest
+	// nonces expire early and their clients are asked to retry with a new one.
+	maxDigestNonces = 10000
+)
+
+// Authenticator requires the requests to be authenticated with HTTP basic or digest authentication
+// before forwarding them to next, with the authenticated user in a header.
+type Authenticator struct {
+	next        http.Handler
+	realm       string
+	headerField string
+	users       *authUsers
+	nonces      *digestNonces
+}
+
+// NewBasicAuth returns a new Authenticator checking HTTP basic authentication credentials against users,
+// in htpasswd format (user:hash), inline or from usersFile. Supported hashes are bcrypt, MD5 (apr1) and SHA1.
+func NewBasicAuth(next http.Handler, realm string, users []string, usersFile string, headerField string) (*Authenticator, error) {
+	authUsers, err := newAuthUsers(users, usersFile, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{next: next, realm: realm, headerField: headerField, users: authUsers}, nil
+}
+
+// NewDigestAuth returns a new Authenticator checking HTTP digest authentication credentials against users,
+// in htdigest format (user:realm:hash), inline or from usersFile.
+func NewDigestAuth(next http.Handler, realm string, users []string, usersFile string, headerField string) (*Authenticator, error) {
+	authUsers, err := newAuthUsers(users, usersFile, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{next: next, realm: realm, headerField: headerField, users: authUsers, nonces: newDigestNonces()}, nil
+}
+
+func (a *Authenticator) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var user string
+	var ok, stale bool
+	if a.nonces != nil {
+		user, ok, stale = a.checkDigest(r)
+	} else {
+		user, ok = a.checkBasic(r)
+	}
+	if !ok {
+		a.challenge(rw, stale)
+		return
+	}
+	r.Header.Set(a.headerField, user)
+	saveUsernameForLogger(r, user)
+	a.next.ServeHTTP(rw, r)
+}
+
+func (a *Authenticator) challenge(rw http.ResponseWriter, stale bool) {
+	if a.nonces != nil {
+		challenge := fmt.Sprintf(`Digest realm="%s", nonce="%s", algorithm=MD5, qop="auth"`, a.realm, a.nonces.create())
+		if stale {
+			challenge += ", stale=true"
+		}
+		rw.Header().Set("WWW-Authenticate", challenge)
+	} else {
+		rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, a.realm))
+	}
+	http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func (a *Authenticator) checkBasic(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	hash, ok := a.users.get(user)
+	if !ok || !checkPassword(password, hash) {
+		return "", false
+	}
+	return user, true
+}
+
+func (a *Authenticator) checkDigest(r *http.Request) (string, bool, bool) {
+	params := parseDigestAuthorization(r.Header.Get("Authorization"))
+	if params == nil || params["realm"] != a.realm || params["qop"] != "auth" {
+		return "", false, false
+	}
+	// the response is only valid for the request-URI sent by the client, before the frontend
+	// strips any prefix
+	if params["uri"] != getRequestURI(r) {
+		return "", false, false
+	}
+	nc, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil {
+		return "", false, false
+	}
+	user := params["username"]
+	ha1, ok := a.users.get(user + ":" + a.realm)
+	if !ok {
+		return "", false, false
+	}
+	ha2 := md5Hex(r.Method + ":" + params["uri"])
+	expected := md5Hex(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(params["response"])) != 1 {
+		return "", false, false
+	}
+	if !a.nonces.use(params["nonce"], nc) {
+		// the credentials are valid, the client can retry with a new nonce
+		return "", false, true
+	}
+	return user, true, false
+}
+
+// checkPassword checks password against a htpasswd hash.
+func checkPassword(password string, hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2y$"), strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$apr1$"):
+		salt := strings.SplitN(strings.TrimPrefix(hash, "$apr1$"), "$", 2)[0]
+		return subtle.ConstantTimeCompare([]byte(apr1Crypt(password, salt)), []byte(hash)) == 1
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte("{SHA}"+base64.StdEncoding.EncodeToString(sum[:])), []byte(hash)) == 1
+	}
+	return false
+}
+
+// apr1Crypt returns the Apache MD5 hash of password with salt.
+func apr1Crypt(password string, salt string) string {
+	const magic = "$apr1$"
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alternate := md5.Sum([]byte(password + salt + password))
+	ctx := md5.New()
+	ctx.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			ctx.Write(alternate[:])
+		} else {
+			ctx.Write(alternate[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pw[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		ctx := md5.New()
+		if i&1 == 1 {
+			ctx.Write(pw)
+		} else {
+			ctx.Write(final)
+		}
+		if i%3 != 0 {
+			ctx.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			ctx.Write(pw)
+		}
+		if i&1 == 1 {
+			ctx.Write(final)
+		} else {
+			ctx.Write(pw)
+		}
+		final = ctx.Sum(nil)
+	}
+
+	encoded := []byte{}
+	encode := func(value uint, n int) {
+		for ; n > 0; n-- {
+			encoded = append(encoded, itoa64[value&0x3f])
+			value >>= 6
+		}
+	}
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(final[group[0]])<<16|uint(final[group[1]])<<8|uint(final[group[2]]), 4)
+	}
+	encode(uint(final[11]), 2)
+	return magic + salt + "$" + string(encoded)
+}
+
+func md5Hex(value string) string {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseDigestAuthorization returns the parameters of a digest Authorization header, or nil if it is not one.
+func parseDigestAuthorization(header string) map[string]string {
+	if !strings.HasPrefix(header, "Digest ") {
+		return nil
+	}
+	params := map[string]string{}
+	remaining := header[len("Digest "):]
+	for {
+		remaining = strings.TrimLeft(remaining, " ,")
+		equal := strings.Index(remaining, "=")
+		if equal == -1 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(remaining[:equal]))
+		remaining = remaining[equal+1:]
+		if strings.HasPrefix(remaining, `"`) {
+			end := strings.Index(remaining[1:], `"`)
+			if end == -1 {
+				return nil
+			}
+			params[key] = remaining[1 : end+1]
+			remaining = remaining[end+2:]
+		} else {
+			end := strings.Index(remaining, ",")
+			if end == -1 {
+				end = len(remaining)
+			}
+			params[key] = strings.TrimSpace(remaining[:end])
+			remaining = remaining[end:]
+		}
+	}
+}
+
+// authUsers holds the users of an Authenticator, reloading the users file when it changes.
+type authUsers struct {
+	inline    map[string]string
+	file      string
+	digest    bool
+	mutex     sync.Mutex
+	fileUsers map[string]string
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+func newAuthUsers(users []string, usersFile string, digest bool) (*authUsers, error) {
+	inline, err := parseAuthUsers(users, digest)
+	if err != nil {
+		return nil, err
+	}
+	authUsers := &authUsers{inline: inline, file: usersFile, digest: digest, fileUsers: map[string]string{}}
+	if len(usersFile) > 0 {
+		if err := authUsers.load(); err != nil {
+			return nil, err
+		}
+	}
+	return authUsers, nil
+}
+
+// get returns the hash of a user, or of a user:realm in digest mode.
+func (au *authUsers) get(key string) (string, bool) {
+	if hash, ok := au.inline[key]; ok {
+		return hash, true
+	}
+	if len(au.file) == 0 {
+		return "", false
+	}
+	au.mutex.Lock()
+	defer au.mutex.Unlock()
+	if time.Since(au.checkedAt) > usersFileCheckPeriod {
+		if err := au.load(); err != nil {
+			log.Errorf("Error reloading users file %s, keeping the previous users: %s", au.file, err)
+		}
+	}
+	hash, ok := au.fileUsers[key]
+	return hash, ok
+}
+
+func (au *authUsers) load() error {
+	au.checkedAt = time.Now()
+	info, err := os.Stat(au.file)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(au.modTime) && info.Size() == au.size {
+		return nil
+	}
+	file, err := os.Open(au.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fileUsers, err := parseAuthUsers(lines, au.digest)
+	if err != nil {
+		return fmt.Errorf("Invalid users file %s: %s", au.file, err)
+	}
+	log.Debugf("Loaded %d users from %s", len(fileUsers), au.file)
+	au.fileUsers = fileUsers
+	au.modTime = info.ModTime()
+	au.size = info.Size()
+	return nil
+}
+
+func parseAuthUsers(lines []string, digest bool) (map[string]string, error) {
+	parts := 2
+	if digest {
+		parts = 3
+	}
+	users := map[string]string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ":", parts)
+		if len(fields) != parts {
+			return nil, fmt.Errorf("invalid user %q", strings.SplitN(line, ":", 2)[0])
+		}
+		users[strings.Join(fields[:parts-1], ":")] = fields[parts-1]
+	}
+	return users, nil
+}
+
+// digestNonces holds the nonces sent to the clients and the last nonce count they used.
+// The nonces are kept in two buckets: the current one, receiving the new nonces, becomes
+// the previous one every digestNonceLifetime, or when it holds maxDigestNonces, and the
+// previous one is dropped with all its nonces, which are expired by then.
+type digestNonces struct {
+	mutex    sync.Mutex
+	current  map[string]*digestNonce
+	previous map[string]*digestNonce
+	timer    *time.Timer
+}
+
+type digestNonce struct {
+	createdAt time.Time
+	count     uint64
+}
+
+func newDigestNonces() *digestNonces {
+	return &digestNonces{current: map[string]*digestNonce{}, previous: map[string]*digestNonce{}}
+}
+
+func (dn *digestNonces) create() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Error generating digest nonce: %s", err)
+	}
+	nonce := hex.EncodeToString(b)
+	dn.mutex.Lock()
+	defer dn.mutex.Unlock()
+	if len(dn.current) >= maxDigestNonces {
+		dn.rotate()
+	}
+	dn.current[nonce] = &digestNonce{createdAt: time.Now()}
+	if dn.timer == nil {
+		dn.timer = time.AfterFunc(digestNonceLifetime, dn.expire)
+	}
+	return nonce
+}
+
+// expire rotates the buckets every digestNonceLifetime, as long as there are nonces.
+func (dn *digestNonces) expire() {
+	dn.mutex.Lock()
+	defer dn.mutex.Unlock()
+	dn.rotate()
+	if len(dn.current) == 0 && len(dn.previous) == 0 {
+		dn.timer = nil
+		return
+	}
+	dn.timer.Reset(digestNonceLifetime)
+}
+
+func (dn *digestNonces) rotate() {
+	dn.previous = dn.current
+	dn.current = map[string]*digestNonce{}
+}
+
+// use returns true if the nonce is known, not expired, and count was not used yet with it.
+func (dn *digestNonces) use(nonce string, count uint64) bool {
+	dn.mutex.Lock()
+	defer dn.mutex.Unlock()
+	value, ok := dn.current[nonce]
+	if !ok {
+		value, ok = dn.previous[nonce]
+	}
+	if !ok || time.Since(value.createdAt) > digestNonceLifetime || count <= value.count {
+		return false
+	}
+	value.count = count
+	return true
+}
@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var authTestHandler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(rw, r.Header.Get("X-Forwarded-User"))
+})
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	hashes := []struct {
+		hash     string
+		expected bool
+	}{
+		{hash: string(bcryptHash), expected: true},
+		{hash: "$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0", expected: true},
+		{hash: "$apr1$saltsalt$LrttParrLPdxvgutaSXWJ1", expected: false},
+		{hash: "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", expected: true},
+		{hash: "secret", expected: false},
+	}
+
+	for _, h := range hashes {
+		assert.Equal(t, h.expected, checkPassword("secret", h.hash), h.hash)
+	}
+	assert.False(t, checkPassword("other", string(bcryptHash)))
+}
+
+func TestBasicAuth(t *testing.T) {
+	auth, err := NewBasicAuth(authTestHandler, "traefik", []string{"test:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="}, "", "X-Forwarded-User")
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	recorder := httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, `Basic realm="traefik"`, recorder.Header().Get("WWW-Authenticate"))
+
+	req.SetBasicAuth("test", "wrong")
+	recorder = httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	req.SetBasicAuth("test", "secret")
+	recorder = httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "test", recorder.Body.String())
+}
+
+func TestBasicAuthUsersFileReload(t *testing.T) {
+	file, err := ioutil.TempFile("", "traefik-users")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("test:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n")
+	file.Close()
+
+	auth, err := NewBasicAuth(authTestHandler, "traefik", nil, file.Name(), "X-Forwarded-User")
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.SetBasicAuth("test", "secret")
+	recorder := httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	assert.NoError(t, ioutil.WriteFile(file.Name(), []byte("other:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0644))
+	os.Chtimes(file.Name(), time.Now(), time.Now().Add(time.Minute))
+	auth.users.checkedAt = time.Time{}
+	recorder = httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestDigestAuth(t *testing.T) {
+	ha1 := md5Hex("test:traefik:secret")
+	auth, err := NewDigestAuth(authTestHandler, "traefik", []string{"test:traefik:" + ha1}, "", "X-Forwarded-User")
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "http://localhost/foo", nil)
+	recorder := httptest.NewRecorder()
+	auth.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	challenge := parseDigestAuthorization(recorder.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, "traefik", challenge["realm"])
+
+	authorize := func(nc string, password string) int {
+		ha1 := md5Hex("test:traefik:" + password)
+		ha2 := md5Hex("GET:/foo")
+		response := md5Hex(strings.Join([]string{ha1, challenge["nonce"], nc, "cnonce", "auth", ha2}, ":"))
+		req.Header.Set("Authorization", fmt.Sprintf(`Digest username="test", realm="traefik", nonce="%s", uri="/foo", qop=auth, nc=%s, cnonce="cnonce", response="%s"`, challenge["nonce"], nc, response))
+		recorder = httptest.NewRecorder()
+		auth.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, authorize("00000001", "wrong"))
+	assert.Equal(t, http.StatusOK, authorize("00000001", "secret"))
+	assert.Equal(t, "test", recorder.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, authorize("00000001", "secret"), "nonce counts cannot be replayed")
+	assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), "stale=true")
+	assert.Equal(t, http.StatusOK, authorize("00000002", "secret"))
+}
+
+func TestDigestAuthRequestURI(t *testing.T) {
+	ha1 := md5Hex("test:traefik:secret")
+	auth, err := NewDigestAuth(authTestHandler, "traefik", []string{"test:traefik:" + ha1}, "", "X-Forwarded-User")
+	assert.NoError(t, err)
+	nonce := auth.nonces.create()
+	// the frontend strips the /prefix of the request-URI before the authentication
+	handler := NewRequestInfo("http")
+	stripPrefix := &StripPrefix{Handler: auth, Prefixes: []string{"/prefix"}}
+
+	authorize := func(path string, uri string, nc string) int {
+		ha2 := md5Hex("GET:" + uri)
+		response := md5Hex(strings.Join([]string{ha1, nonce, nc, "cnonce", "auth", ha2}, ":"))
+		req, _ := http.NewRequest("GET", "http://localhost"+path, nil)
+		req.RequestURI = path
+		req.Header.Set("Authorization", fmt.Sprintf(`Digest username="test", realm="traefik", nonce="%s", uri="%s", qop=auth, nc=%s, cnonce="cnonce", response="%s"`, nonce, uri, nc, response))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req, stripPrefix.ServeHTTP)
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, authorize("/prefix/foo", "/foo", "00000001"), "the uri is the one sent by the client")
+	assert.Equal(t, http.StatusUnauthorized, authorize("/prefix/foo", "/other/prefix/foo", "00000001"), "the uri must match exactly")
+	assert.Equal(t, http.StatusOK, authorize("/prefix/foo", "/prefix/foo", "00000001"))
+	assert.Equal(t, http.StatusUnauthorized, authorize("/prefix/bar", "/prefix/foo", "00000002"), "a response is only valid for its uri")
+}
+
+func TestDigestNoncesBounded(t *testing.T) {
+	nonces := newDigestNonces()
+	first := nonces.create()
+	for i := 1; i < maxDigestNonces; i++ {
+		nonces.create()
+	}
+	assert.True(t, nonces.use(first, 1), "nonces are kept in the previous bucket")
+	for i := 0; i <= maxDigestNonces; i++ {
+		nonces.create()
+	}
+	assert.False(t, nonces.use(first, 2), "the oldest nonces are dropped past maxDigestNonces")
+	assert.Len(t, nonces.current, 1)
+	assert.Len(t, nonces.previous, maxDigestNonces)
+
+	nonces.expire()
+	nonces.expire()
+	assert.Empty(t, nonces.current)
+	assert.Empty(t, nonces.previous)
+	assert.Nil(t, nonces.timer, "no timer is left once all the nonces expired")
+}
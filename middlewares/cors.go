@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// CORSOptions holds the Cross-Origin Resource Sharing policy applied by CORS.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// CORS answers the CORS preflight requests without forwarding them, and sets the CORS headers on the
+// responses to the requests from the allowed origins.
+// An allowed origin can hold a wildcard, like "https://*.example.com", and "*" allows any origin,
+// but not along with credentials, which would then be shared with any site.
+// The allowed methods default to GET, HEAD and POST, and "*" in the allowed headers allows any header.
+type CORS struct {
+	next    http.Handler
+	options CORSOptions
+}
+
+// NewCORS returns a new CORS forwarding the requests other than preflights to next.
+func NewCORS(next http.Handler, options CORSOptions) (*CORS, error) {
+	if options.AllowCredentials {
+		for _, allowedOrigin := range options.AllowedOrigins {
+			if allowedOrigin == "*" {
+				return nil, errors.New("Credentials cannot be allowed for any origin")
+			}
+		}
+	}
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = []string{"GET", "HEAD", "POST"}
+	}
+	return &CORS{next: next, options: options}, nil
+}
+
+func (c *CORS) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if r.Method == "OPTIONS" && len(origin) > 0 && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+		c.preflight(rw, r, origin)
+		return
+	}
+	rw.Header().Add("Vary", "Origin")
+	if len(origin) == 0 || !c.allowedOrigin(origin) {
+		c.next.ServeHTTP(rw, r)
+		return
+	}
+	c.next.ServeHTTP(&headersResponseWriter{ResponseWriter: rw, setHeaders: func(header http.Header) {
+		c.setAllowOrigin(header, origin)
+		if len(c.options.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(c.options.ExposedHeaders, ", "))
+		}
+	}}, r)
+}
+
+func (c *CORS) preflight(rw http.ResponseWriter, r *http.Request, origin string) {
+	header := rw.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	requestHeaders := splitHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+	if !c.allowedOrigin(origin) || !c.allowedMethod(method) || !c.allowedHeaders(requestHeaders) {
+		log.Debugf("Rejecting CORS preflight from origin %s for method %s and headers %v", origin, method, requestHeaders)
+		rw.WriteHeader(http.StatusForbidden)
+		return
+	}
+	c.setAllowOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(c.options.AllowedMethods, ", "))
+	if len(requestHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+	}
+	if c.options.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(c.options.MaxAge))
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// setAllowOrigin allows origin in the response headers, or any origin if they are all allowed,
+// which is never the case with credentials.
+func (c *CORS) setAllowOrigin(header http.Header, origin string) {
+	if c.options.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+		return
+	}
+	for _, allowedOrigin := range c.options.AllowedOrigins {
+		if allowedOrigin == "*" {
+			header.Set("Access-Control-Allow-Origin", "*")
+			return
+		}
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *CORS) allowedOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowedOrigin := range c.options.AllowedOrigins {
+		allowedOrigin = strings.ToLower(allowedOrigin)
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			return true
+		}
+		if wildcard := strings.Index(allowedOrigin, "*"); wildcard >= 0 {
+			prefix, suffix := allowedOrigin[:wildcard], allowedOrigin[wildcard+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowedMethod(method string) bool {
+	for _, allowedMethod := range c.options.AllowedMethods {
+		if allowedMethod == "*" || strings.EqualFold(allowedMethod, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowedHeaders(headers []string) bool {
+	for _, header := range headers {
+		allowed := false
+		for _, allowedHeader := range c.options.AllowedHeaders {
+			if allowedHeader == "*" || strings.EqualFold(allowedHeader, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func splitHeaderList(value string) []string {
+	headers := []string{}
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); len(header) > 0 {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	forwarded := false
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		forwarded = true
+	})
+	cors, err := NewCORS(handler, CORSOptions{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Content-Type", "X-Requested-With"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	assert.NoError(t, err)
+
+	preflight := func(origin string, method string, headers string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("OPTIONS", "http://api.localhost/users", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		recorder := httptest.NewRecorder()
+		cors.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := preflight("https://app.example.com", "PUT", "content-type")
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, PUT", recorder.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", recorder.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+
+	assert.Equal(t, http.StatusForbidden, preflight("https://example.org", "PUT", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "DELETE", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "GET", "Authorization").Code)
+	assert.False(t, forwarded, "preflights are not forwarded")
+}
+
+func TestCORSRequest(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("ok"))
+	})
+	cors, err := NewCORS(handler, CORSOptions{AllowedOrigins: []string{"*"}, ExposedHeaders: []string{"X-Total-Count"}})
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "http://api.localhost/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	recorder := httptest.NewRecorder()
+	cors.ServeHTTP(recorder, req)
+	assert.Equal(t, "ok", recorder.Body.String())
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total-Count", recorder.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+
+	req.Header.Del("Origin")
+	recorder = httptest.NewRecorder()
+	cors.ServeHTTP(recorder, req)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAnyOriginWithCredentials(t *testing.T) {
+	_, err := NewCORS(http.NotFoundHandler(), CORSOptions{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+	assert.Error(t, err, "credentials cannot be shared with any origin")
+
+	_, err = NewCORS(http.NotFoundHandler(), CORSOptions{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true})
+	assert.NoError(t, err)
+}
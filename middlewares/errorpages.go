@@ -0,0 +1,176 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ErrorPages replaces the responses of a frontend whose status code matches one of its pages
+// with the page served by an error backend. The original status code is kept.
+type ErrorPages struct {
+	next  http.Handler
+	pages []*errorPage
+}
+
+type errorPage struct {
+	backend http.Handler
+	query   string
+	ranges  [][2]int
+}
+
+// NewErrorPages returns a new ErrorPages forwarding requests to next.
+func NewErrorPages(next http.Handler) *ErrorPages {
+	return &ErrorPages{next: next}
+}
+
+// AddPage adds a page served from backend at query for the status codes or ranges of status codes
+// (like "404" or "500-599") in statuses. The {status} placeholder of query is replaced with the status code.
+// Pages are matched in the order they are added.
+func (ep *ErrorPages) AddPage(statuses []string, backend http.Handler, query string) error {
+	page := &errorPage{backend: backend, query: query}
+	for _, status := range statuses {
+		bounds := strings.SplitN(status, "-", 2)
+		from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return fmt.Errorf("Invalid status %s: %s", status, err)
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return fmt.Errorf("Invalid status %s: %s", status, err)
+			}
+		}
+		page.ranges = append(page.ranges, [2]int{from, to})
+	}
+	ep.pages = append(ep.pages, page)
+	return nil
+}
+
+func (ep *ErrorPages) match(status int) *errorPage {
+	for _, page := range ep.pages {
+		for _, statusRange := range page.ranges {
+			if status >= statusRange[0] && status <= statusRange[1] {
+				return page
+			}
+		}
+	}
+	return nil
+}
+
+func (ep *ErrorPages) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	interceptor := &errorPagesResponseWriter{ResponseWriter: rw, header: http.Header{}, errorPages: ep}
+	ep.next.ServeHTTP(interceptor, r)
+	if interceptor.status == 0 {
+		interceptor.WriteHeader(http.StatusOK)
+	}
+	if interceptor.page == nil {
+		return
+	}
+
+	query := strings.Replace(interceptor.page.query, "{status}", strconv.Itoa(interceptor.status), -1)
+	pageURL, err := url.Parse(query)
+	if err != nil {
+		log.Errorf("Invalid error page query %s: %s", query, err)
+		rw.WriteHeader(interceptor.status)
+		return
+	}
+	pageReq, err := http.NewRequest("GET", pageURL.String(), nil)
+	if err != nil {
+		log.Errorf("Error creating request for error page %s: %s", query, err)
+		rw.WriteHeader(interceptor.status)
+		return
+	}
+	pageReq.RequestURI = pageURL.RequestURI()
+	pageReq.RemoteAddr = r.RemoteAddr
+	pageWriter := &statusOverrideResponseWriter{ResponseWriter: rw, status: interceptor.status}
+	interceptor.page.backend.ServeHTTP(pageWriter, pageReq)
+	if !pageWriter.wroteHeader {
+		pageWriter.WriteHeader(interceptor.status)
+	}
+}
+
+// errorPagesResponseWriter holds back the headers and the body of a response
+// until its status code is known, and discards them if an error page is served instead.
+type errorPagesResponseWriter struct {
+	http.ResponseWriter
+	header     http.Header
+	status     int
+	errorPages *ErrorPages
+	page       *errorPage
+}
+
+func (epw *errorPagesResponseWriter) Header() http.Header {
+	return epw.header
+}
+
+func (epw *errorPagesResponseWriter) Write(b []byte) (int, error) {
+	if epw.status == 0 {
+		epw.WriteHeader(http.StatusOK)
+	}
+	if epw.page != nil {
+		return len(b), nil
+	}
+	return epw.ResponseWriter.Write(b)
+}
+
+func (epw *errorPagesResponseWriter) WriteHeader(status int) {
+	if epw.status != 0 {
+		return
+	}
+	epw.status = status
+	epw.page = epw.errorPages.match(status)
+	if epw.page != nil {
+		return
+	}
+	for key, values := range epw.header {
+		epw.ResponseWriter.Header()[key] = values
+	}
+	epw.ResponseWriter.WriteHeader(status)
+}
+
+func (epw *errorPagesResponseWriter) Flush() {
+	if epw.page != nil {
+		return
+	}
+	if flusher, ok := epw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (epw *errorPagesResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return epw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (epw *errorPagesResponseWriter) CloseNotify() <-chan bool {
+	return epw.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// statusOverrideResponseWriter is a http.ResponseWriter writing a given status code
+// whatever the status code of the response.
+type statusOverrideResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sow *statusOverrideResponseWriter) Write(b []byte) (int, error) {
+	if !sow.wroteHeader {
+		sow.WriteHeader(sow.status)
+	}
+	return sow.ResponseWriter.Write(b)
+}
+
+func (sow *statusOverrideResponseWriter) WriteHeader(status int) {
+	if sow.wroteHeader {
+		return
+	}
+	sow.wroteHeader = true
+	sow.ResponseWriter.WriteHeader(sow.status)
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestErrorPages(status int) *ErrorPages {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Backend", "app")
+		rw.WriteHeader(status)
+		fmt.Fprint(rw, "app")
+	})
+	pageBackend := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/html")
+		rw.WriteHeader(http.StatusOK)
+		fmt.Fprintf(rw, "page %s", r.URL.Path)
+	})
+	errorPages := NewErrorPages(handler)
+	errorPages.AddPage([]string{"404"}, pageBackend, "/notfound.html")
+	errorPages.AddPage([]string{"500-599"}, pageBackend, "/{status}.html")
+	return errorPages
+}
+
+func TestErrorPages(t *testing.T) {
+	tests := []struct {
+		status       int
+		expectedBody string
+	}{
+		{status: http.StatusOK, expectedBody: "app"},
+		{status: http.StatusNotFound, expectedBody: "page /notfound.html"},
+		{status: http.StatusBadGateway, expectedBody: "page /502.html"},
+		{status: http.StatusForbidden, expectedBody: "app"},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		recorder := httptest.NewRecorder()
+		newTestErrorPages(test.status).ServeHTTP(recorder, req)
+		assert.Equal(t, test.status, recorder.Code, "the original status code is kept")
+		assert.Equal(t, test.expectedBody, recorder.Body.String())
+		if test.expectedBody == "app" {
+			assert.Equal(t, "app", recorder.Header().Get("X-Backend"))
+		} else {
+			assert.Empty(t, recorder.Header().Get("X-Backend"), "the headers of the original response are discarded")
+			assert.Equal(t, "text/html", recorder.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestErrorPagesInvalidStatus(t *testing.T) {
+	errorPages := NewErrorPages(http.NotFoundHandler())
+	assert.Error(t, errorPages.AddPage([]string{"5xx"}, http.NotFoundHandler(), "/"))
+	assert.Error(t, errorPages.AddPage([]string{"500-"}, http.NotFoundHandler(), "/"))
+}
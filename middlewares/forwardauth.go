@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailers",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Timeouts of the calls to the authentication service, which would otherwise hold the requests forever
+const (
+	forwardAuthDialTimeout           = 10 * time.Second
+	forwardAuthTLSHandshakeTimeout   = 10 * time.Second
+	forwardAuthResponseHeaderTimeout = 30 * time.Second
+)
+
+// ForwardAuth sends each request to an authentication service before forwarding it.
+// A 2xx response lets the request through, with the selected headers of the response,
+// any other response is returned to the client.
+type ForwardAuth struct {
+	address             string
+	transport           *http.Transport
+	trustForwardHeader  bool
+	authResponseHeaders []string
+}
+
+// NewForwardAuth returns a new ForwardAuth using the authentication service at address.
+func NewForwardAuth(address string, tlsConfig *tls.Config, trustForwardHeader bool, authResponseHeaders []string) (*ForwardAuth, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, errors.New("Invalid authentication service address " + address)
+	}
+	return &ForwardAuth{
+		address: address,
+		// the redirections of the authentication service are returned to the client
+		transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   forwardAuthDialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).Dial,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   forwardAuthTLSHandshakeTimeout,
+			ResponseHeaderTimeout: forwardAuthResponseHeaderTimeout,
+		},
+		trustForwardHeader:  trustForwardHeader,
+		authResponseHeaders: authResponseHeaders,
+	}, nil
+}
+
+// CloseIdleConnections closes the connections kept open to the authentication service,
+// once the ForwardAuth is no longer used.
+func (fa *ForwardAuth) CloseIdleConnections() {
+	fa.transport.CloseIdleConnections()
+}
+
+func (fa *ForwardAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	authReq, err := http.NewRequest("GET", fa.address, nil)
+	if err != nil {
+		log.Errorf("Error creating request to authentication service %s: %s", fa.address, err)
+		http.Error(rw, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	fa.copyRequestHeaders(authReq, r)
+
+	authResp, err := fa.transport.RoundTrip(authReq)
+	if err != nil {
+		log.Errorf("Error calling authentication service %s: %s", fa.address, err)
+		status := http.StatusBadGateway
+		if opErr, ok := err.(*net.OpError); ok && opErr.Op == "dial" {
+			// the authentication service cannot be reached at all
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(rw, http.StatusText(status), status)
+		return
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode >= 200 && authResp.StatusCode < 300 {
+		for _, header := range fa.authResponseHeaders {
+			if value := authResp.Header.Get(header); len(value) > 0 {
+				r.Header.Set(header, value)
+			} else {
+				r.Header.Del(header)
+			}
+		}
+		next(rw, r)
+		return
+	}
+
+	log.Debugf("Request to %s%s denied by authentication service %s with status %d", r.Host, r.RequestURI, fa.address, authResp.StatusCode)
+	for key, values := range authResp.Header {
+		rw.Header()[key] = values
+	}
+	removeHopHeaders(rw.Header())
+	rw.WriteHeader(authResp.StatusCode)
+	io.Copy(rw, authResp.Body)
+}
+
+func (fa *ForwardAuth) copyRequestHeaders(authReq *http.Request, r *http.Request) {
+	for key, values := range r.Header {
+		authReq.Header[key] = values
+	}
+	removeHopHeaders(authReq.Header)
+	// the key of the request information is only meaningful to this instance
+	delete(authReq.Header, requestInfoHeader)
+
+	forwardedFor, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		forwardedFor = r.RemoteAddr
+	}
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	forwarded := map[string]string{
+		"X-Forwarded-For":    forwardedFor,
+		"X-Forwarded-Proto":  proto,
+		"X-Forwarded-Host":   r.Host,
+		"X-Forwarded-Uri":    r.RequestURI,
+		"X-Forwarded-Method": r.Method,
+	}
+	for header, value := range forwarded {
+		if !fa.trustForwardHeader || len(authReq.Header.Get(header)) == 0 {
+			authReq.Header.Set(header, value)
+		}
+	}
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, hopHeader := range hopHeaders {
+		header.Del(hopHeader)
+	}
+}
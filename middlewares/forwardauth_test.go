@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardAuth(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "session=valid" {
+			rw.Header().Set("Location", "https://sso.localhost/login?rd="+r.Header.Get("X-Forwarded-Host")+r.Header.Get("X-Forwarded-Uri"))
+			rw.WriteHeader(http.StatusFound)
+			return
+		}
+		rw.Header().Set("X-Auth-User", "test")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer authServer.Close()
+
+	forwardAuth, err := NewForwardAuth(authServer.URL, nil, false, []string{"X-Auth-User", "X-Auth-Groups"})
+	assert.NoError(t, err)
+	next := func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(rw, "%s %s", r.Header.Get("X-Auth-User"), r.Header.Get("X-Auth-Groups"))
+	}
+
+	req, _ := http.NewRequest("GET", "http://app.localhost/foo", nil)
+	req.Host = "app.localhost"
+	req.RequestURI = "/foo"
+	recorder := httptest.NewRecorder()
+	forwardAuth.ServeHTTP(recorder, req, next)
+	assert.Equal(t, http.StatusFound, recorder.Code)
+	assert.Equal(t, "https://sso.localhost/login?rd=app.localhost/foo", recorder.Header().Get("Location"))
+
+	req.Header.Set("Cookie", "session=valid")
+	req.Header.Set("X-Auth-Groups", "admin")
+	recorder = httptest.NewRecorder()
+	forwardAuth.ServeHTTP(recorder, req, next)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "test ", recorder.Body.String(), "the copied headers cannot be set by the client")
+}
+
+func TestForwardAuthTrustForwardHeader(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer authServer.Close()
+
+	req, _ := http.NewRequest("GET", "http://app.localhost/", nil)
+	req.Host = "app.localhost"
+	req.Header.Set("X-Forwarded-Host", "forwarded.localhost")
+	for trust, expected := range map[bool]string{true: "forwarded.localhost", false: "app.localhost"} {
+		authReq, _ := http.NewRequest("GET", authServer.URL, nil)
+		forwardAuth, err := NewForwardAuth(authServer.URL, nil, trust, nil)
+		assert.NoError(t, err)
+		forwardAuth.copyRequestHeaders(authReq, req)
+		assert.Equal(t, expected, authReq.Header.Get("X-Forwarded-Host"))
+	}
+}
+
+func TestForwardAuthInternalHeaders(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, r.Header.Get(requestInfoHeader))
+	}))
+	defer authServer.Close()
+
+	forwardAuth, err := NewForwardAuth(authServer.URL, nil, true, nil)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://app.localhost/", nil)
+	req.Header.Set(requestInfoHeader, "1")
+	authReq, _ := http.NewRequest("GET", authServer.URL, nil)
+	forwardAuth.copyRequestHeaders(authReq, req)
+	assert.Empty(t, authReq.Header.Get(requestInfoHeader))
+	assert.Equal(t, "1", req.Header.Get(requestInfoHeader), "the request forwarded to the backend is not changed")
+}
+
+func TestForwardAuthUnavailable(t *testing.T) {
+	next := func(rw http.ResponseWriter, r *http.Request) {
+		t.Error("the request must not be forwarded without authentication")
+	}
+
+	// a closed server refuses the connections
+	authServer := httptest.NewServer(http.NotFoundHandler())
+	authServer.Close()
+	forwardAuth, err := NewForwardAuth(authServer.URL, nil, false, nil)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://app.localhost/", nil)
+	recorder := httptest.NewRecorder()
+	forwardAuth.ServeHTTP(recorder, req, next)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	// a server closing the connections without response
+	authServer = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, _, _ := rw.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer authServer.Close()
+	forwardAuth, err = NewForwardAuth(authServer.URL, nil, false, nil)
+	assert.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	forwardAuth.ServeHTTP(recorder, req, next)
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	forwardAuth.CloseIdleConnections()
+}
+
+func TestNewForwardAuthInvalidAddress(t *testing.T) {
+	for _, address := range []string{"", "auth.localhost/verify", "ftp://auth.localhost/", "http://"} {
+		_, err := NewForwardAuth(address, nil, false, nil)
+		assert.Error(t, err, address)
+	}
+}
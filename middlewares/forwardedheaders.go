@@ -0,0 +1,202 @@
+package middlewares
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/containous/oxy/forward"
+)
+
+// forwardedHeaderNames are the headers set by the proxies in front of an entry point.
+var forwardedHeaderNames = []string{
+	"Forwarded",
+	forward.XForwardedFor,
+	forward.XForwardedProto,
+	forward.XForwardedHost,
+	"X-Forwarded-Port",
+	forward.XForwardedServer,
+	"X-Real-Ip",
+}
+
+// ForwardedHeaders is the negroni handler accepting the X-Forwarded-* and RFC 7239 Forwarded headers
+// of the requests coming from trusted proxies, and removing them from the other requests.
+// The address of the proxy is appended to the accepted X-Forwarded-For, and the remote address
+// of the request is replaced by the client IP, which is the last untrusted address of the chain.
+type ForwardedHeaders struct {
+	insecure    bool
+	passthrough bool
+	trustedIPs  []*net.IPNet
+}
+
+// NewForwardedHeaders returns a new ForwardedHeaders trusting all the proxies if insecure is true,
+// or only the proxies in trustedIPs, which are IPs or CIDRs.
+func NewForwardedHeaders(insecure bool, trustedIPs []string) (*ForwardedHeaders, error) {
+	ipNets, err := parseSourceRanges(trustedIPs)
+	if err != nil {
+		return nil, err
+	}
+	return &ForwardedHeaders{
+		insecure:   insecure,
+		trustedIPs: ipNets,
+	}, nil
+}
+
+// NewForwardedHeadersPassthrough returns a new ForwardedHeaders keeping the headers of all the requests,
+// for the entry points which trust no proxy explicitly. Since the clients can set them to anything,
+// the remote address of the requests is kept.
+func NewForwardedHeadersPassthrough() *ForwardedHeaders {
+	return &ForwardedHeaders{insecure: true, passthrough: true}
+}
+
+func (fh *ForwardedHeaders) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	peer, port, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !fh.trusted(peer) {
+		for _, header := range forwardedHeaderNames {
+			r.Header.Del(header)
+		}
+		next(rw, r)
+		return
+	}
+
+	if len(r.Header[forward.XForwardedFor]) == 0 && len(r.Header["Forwarded"]) > 0 {
+		fh.setForwardedHeaders(r)
+	}
+	chain := []string{}
+	for _, value := range r.Header[forward.XForwardedFor] {
+		for _, ip := range strings.Split(value, ",") {
+			if ip = strings.TrimSpace(ip); len(ip) > 0 {
+				chain = append(chain, ip)
+			}
+		}
+	}
+	chain = append(chain, peer)
+	r.Header.Set(forward.XForwardedFor, strings.Join(chain, ", "))
+
+	// the chain is walked back from the proxy, until the first address which is not a trusted proxy
+	client := peer
+	for i := len(chain) - 2; i >= 0 && fh.trusted(client); i-- {
+		if net.ParseIP(chain[i]) == nil {
+			break
+		}
+		client = chain[i]
+	}
+	if client != peer && !fh.passthrough {
+		r.RemoteAddr = net.JoinHostPort(client, port)
+	}
+	next(rw, r)
+}
+
+// trusted returns true if the address is a trusted proxy.
+func (fh *ForwardedHeaders) trusted(address string) bool {
+	if fh.insecure {
+		return true
+	}
+	ip := net.ParseIP(address)
+	return ip != nil && containsIP(fh.trustedIPs, ip)
+}
+
+// setForwardedHeaders sets the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers from the
+// RFC 7239 Forwarded header, like `for=192.0.2.43;proto=https;host=example.com, for="[2001:db8::1]:4711"`.
+// The protocol and host are the ones of the first element, received from the client.
+func (fh *ForwardedHeaders) setForwardedHeaders(r *http.Request) {
+	forwardedFor := []string{}
+	proto, host := "", ""
+	for i, element := range strings.Split(strings.Join(r.Header["Forwarded"], ","), ",") {
+		for _, pair := range strings.Split(element, ";") {
+			keyValue := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			value := strings.Trim(keyValue[1], `"`)
+			switch strings.ToLower(keyValue[0]) {
+			case "for":
+				if strings.HasPrefix(value, "[") {
+					value = strings.TrimPrefix(strings.SplitN(value, "]", 2)[0], "[")
+				} else if ip, _, err := net.SplitHostPort(value); err == nil {
+					value = ip
+				}
+				forwardedFor = append(forwardedFor, value)
+			case "proto":
+				if i == 0 {
+					proto = value
+				}
+			case "host":
+				if i == 0 {
+					host = value
+				}
+			}
+		}
+	}
+	if len(forwardedFor) > 0 {
+		r.Header.Set(forward.XForwardedFor, strings.Join(forwardedFor, ", "))
+	}
+	if len(proto) > 0 && len(r.Header.Get(forward.XForwardedProto)) == 0 {
+		r.Header.Set(forward.XForwardedProto, proto)
+	}
+	if len(host) > 0 && len(r.Header.Get(forward.XForwardedHost)) == 0 {
+		r.Header.Set(forward.XForwardedHost, host)
+	}
+}
+
+// ForwardedHeadersRewriter sets the X-Forwarded-* headers of the requests forwarded to the backends.
+// It keeps the X-Forwarded-For chain built by ForwardedHeaders, which already ends with the address
+// of the proxy, instead of appending the remote address, which is the client IP.
+type ForwardedHeadersRewriter struct {
+	headerRewriter *forward.HeaderRewriter
+}
+
+// NewForwardedHeadersRewriter returns a new ForwardedHeadersRewriter.
+func NewForwardedHeadersRewriter() *ForwardedHeadersRewriter {
+	hostname, _ := os.Hostname()
+	return &ForwardedHeadersRewriter{
+		headerRewriter: &forward.HeaderRewriter{TrustForwardHeader: true, Hostname: hostname},
+	}
+}
+
+// Rewrite rewrites the headers of the request forwarded to a backend, a copy of the original request.
+func (rw *ForwardedHeadersRewriter) Rewrite(r *http.Request) {
+	removeRequestInfo(r)
+	forwardedFor := r.Header[forward.XForwardedFor]
+	rw.headerRewriter.Rewrite(r)
+	if len(forwardedFor) > 0 {
+		r.Header[forward.XForwardedFor] = forwardedFor
+	}
+}
+
+// parseSourceRanges parses a list of IPs or CIDRs.
+func parseSourceRanges(sourceRanges []string) ([]*net.IPNet, error) {
+	ipNets := []*net.IPNet{}
+	for _, sourceRange := range sourceRanges {
+		if !strings.Contains(sourceRange, "/") {
+			if ip := net.ParseIP(sourceRange); ip == nil {
+				return nil, errors.New("Invalid source range " + sourceRange)
+			} else if ip.To4() != nil {
+				sourceRange += "/32"
+			} else {
+				sourceRange += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(sourceRange)
+		if err != nil {
+			return nil, errors.New("Invalid source range " + sourceRange)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// containsIP returns true if the IP is in one of the networks.
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
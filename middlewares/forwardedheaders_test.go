@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardedHeaders(t *testing.T) {
+	forwardedHeaders, err := NewForwardedHeaders(false, []string{"10.0.0.0/8", "192.168.1.1"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		desc         string
+		remoteAddr   string
+		header       map[string]string
+		forwardedFor string
+		proto        string
+		host         string
+		remoteIP     string
+	}{
+		{
+			desc:         "untrusted proxy",
+			remoteAddr:   "1.2.3.4:1234",
+			header:       map[string]string{"X-Forwarded-For": "6.6.6.6", "X-Forwarded-Proto": "https", "Forwarded": "for=6.6.6.6"},
+			forwardedFor: "",
+			remoteIP:     "1.2.3.4",
+		},
+		{
+			desc:         "trusted proxies",
+			remoteAddr:   "192.168.1.1:1234",
+			header:       map[string]string{"X-Forwarded-For": "6.6.6.6, 5.6.7.8, 10.0.0.2", "X-Forwarded-Proto": "https"},
+			forwardedFor: "6.6.6.6, 5.6.7.8, 10.0.0.2, 192.168.1.1",
+			proto:        "https",
+			remoteIP:     "5.6.7.8",
+		},
+		{
+			desc:         "RFC 7239 header",
+			remoteAddr:   "10.0.0.1:1234",
+			header:       map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https;host=test.localhost, for=10.0.0.2`},
+			forwardedFor: "2001:db8::1, 10.0.0.2, 10.0.0.1",
+			proto:        "https",
+			host:         "test.localhost",
+			remoteIP:     "2001:db8::1",
+		},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+		req.RemoteAddr = test.remoteAddr
+		for name, value := range test.header {
+			req.Header.Set(name, value)
+		}
+		var remoteAddr string
+		forwardedHeaders.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+			remoteAddr = r.RemoteAddr
+		})
+		assert.Equal(t, test.forwardedFor, req.Header.Get("X-Forwarded-For"), test.desc)
+		assert.Equal(t, test.proto, req.Header.Get("X-Forwarded-Proto"), test.desc)
+		assert.Equal(t, test.host, req.Header.Get("X-Forwarded-Host"), test.desc)
+		remoteIP, _, _ := net.SplitHostPort(remoteAddr)
+		assert.Equal(t, test.remoteIP, remoteIP, test.desc)
+
+		NewForwardedHeadersRewriter().Rewrite(req)
+		if len(test.forwardedFor) > 0 {
+			assert.Equal(t, test.forwardedFor, req.Header.Get("X-Forwarded-For"), test.desc)
+		} else {
+			assert.Equal(t, test.remoteIP, req.Header.Get("X-Forwarded-For"), test.desc)
+		}
+	}
+}
+
+func TestForwardedHeadersPassthrough(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	var remoteAddr string
+	NewForwardedHeadersPassthrough().ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	})
+	assert.Equal(t, "6.6.6.6, 1.2.3.4", req.Header.Get("X-Forwarded-For"))
+	assert.Equal(t, "https", req.Header.Get("X-Forwarded-Proto"))
+	assert.Equal(t, "1.2.3.4:1234", remoteAddr, "the client IP is not taken from untrusted headers")
+
+	// the client IP is resolved when all the clients are explicitly trusted
+	forwardedHeaders, err := NewForwardedHeaders(true, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+	forwardedHeaders.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	})
+	assert.Equal(t, "6.6.6.6:1234", remoteAddr)
+}
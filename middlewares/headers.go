@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Headers sets and removes headers on the requests of a frontend and on their responses.
+// The values of the custom headers can hold the {client.ip}, {frontend.name}, {request.host}
+// and {request.id} placeholders. The request ID header cannot be set or removed.
+type Headers struct {
+	next                  http.Handler
+	frontend              string
+	customRequestHeaders  map[string]string
+	customResponseHeaders map[string]string
+	removeRequestHeaders  []string
+	removeResponseHeaders []string
+}
+
+// NewHeaders returns a new Headers for the frontend named frontend, forwarding requests to next.
+func NewHeaders(next http.Handler, frontend string, customRequestHeaders map[string]string, customResponseHeaders map[string]string, removeRequestHeaders []string, removeResponseHeaders []string) *Headers {
+	return &Headers{
+		next:                  next,
+		frontend:              frontend,
+		customRequestHeaders:  customRequestHeaders,
+		customResponseHeaders: customResponseHeaders,
+		removeRequestHeaders:  removeRequestHeaders,
+		removeResponseHeaders: removeResponseHeaders,
+	}
+}
+
+func (h *Headers) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	replacer := h.replacer(r)
+	for _, header := range h.removeRequestHeaders {
+		if !protectedRequestHeader(r, header) {
+			r.Header.Del(header)
+		}
+	}
+	for header, value := range h.customRequestHeaders {
+		if !protectedRequestHeader(r, header) {
+			r.Header.Set(header, replacer.Replace(value))
+		}
+	}
+	if len(h.customResponseHeaders) == 0 && len(h.removeResponseHeaders) == 0 {
+		h.next.ServeHTTP(rw, r)
+		return
+	}
+	h.next.ServeHTTP(&headersResponseWriter{ResponseWriter: rw, setHeaders: func(header http.Header) {
+		for _, name := range h.removeResponseHeaders {
+			header.Del(name)
+		}
+		for name, value := range h.customResponseHeaders {
+			header.Set(name, replacer.Replace(value))
+		}
+	}}, r)
+}
+
+func (h *Headers) replacer(r *http.Request) *strings.Replacer {
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+	_, requestID := getRequestID(r)
+	return strings.NewReplacer(
+		"{client.ip}", clientIP,
+		"{frontend.name}", h.frontend,
+		"{request.host}", r.Host,
+		"{request.id}", requestID,
+	)
+}
+
+// protectedRequestHeader returns true for the headers identifying the requests, left untouched
+// for the access log, the metrics and the tracing.
+func protectedRequestHeader(r *http.Request, header string) bool {
+	header = http.CanonicalHeaderKey(header)
+	requestIDHeader, _ := getRequestID(r)
+	return header == requestInfoHeader || header == requestIDHeader
+}
+
+// headersResponseWriter calls setHeaders on the response headers right before the status code is written.
+type headersResponseWriter struct {
+	http.ResponseWriter
+	setHeaders  func(http.Header)
+	wroteHeader bool
+}
+
+func (hrw *headersResponseWriter) Write(b []byte) (int, error) {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	return hrw.ResponseWriter.Write(b)
+}
+
+func (hrw *headersResponseWriter) WriteHeader(status int) {
+	if !hrw.wroteHeader {
+		hrw.wroteHeader = true
+		hrw.setHeaders(hrw.Header())
+	}
+	hrw.ResponseWriter.WriteHeader(status)
+}
+
+func (hrw *headersResponseWriter) Flush() {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := hrw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (hrw *headersResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hrw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (hrw *headersResponseWriter) CloseNotify() <-chan bool {
+	return hrw.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
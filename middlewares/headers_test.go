@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaders(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Server", "app/1.0")
+		rw.Header().Set("X-Powered-By", "php")
+		rw.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(rw, "%s|%s|%s", r.Header.Get("X-Forwarded-Prefix"), r.Header.Get("X-Client"), r.Header.Get("X-Secret"))
+	})
+	headers := NewHeaders(handler, "frontend1",
+		map[string]string{"X-Forwarded-Prefix": "/api", "X-Client": "{client.ip} via {frontend.name}"},
+		map[string]string{"X-Frontend": "{frontend.name}", "X-Host": "{request.host}"},
+		[]string{"X-Secret"},
+		[]string{"Server", "X-Powered-By"})
+
+	req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+	req.Header.Set("X-Secret", "secret")
+	recorder := httptest.NewRecorder()
+	headers.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "/api|10.0.0.1 via frontend1|", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Server"))
+	assert.Empty(t, recorder.Header().Get("X-Powered-By"))
+	assert.Equal(t, "text/plain", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "frontend1", recorder.Header().Get("X-Frontend"))
+	assert.Equal(t, "test.localhost", recorder.Header().Get("X-Host"))
+}
+
+func TestHeadersRequestID(t *testing.T) {
+	var requestID string
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get("X-Request-ID")
+	})
+	headers := NewHeaders(handler, "frontend1", map[string]string{"X-Request-Id": "fixed"}, nil, []string{"X-Request-ID"}, nil)
+
+	req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+	req.Header.Set("X-Request-ID", "request1")
+	serveWithRequestID(httptest.NewRecorder(), req, headers.ServeHTTP)
+	assert.Equal(t, "request1", requestID, "the request ID header is left untouched")
+}
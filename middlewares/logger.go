@@ -2,21 +2,22 @@ package middlewares
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
-	log "github.com/Sirupsen/logrus"
-	"github.com/streamrail/concurrent-map"
-	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"text/template"
 	"time"
-)
 
-const (
-	loggerReqidHeader = "X-Traefik-Reqid"
+	log "github.com/Sirupsen/logrus"
 )
 
 /*
@@ -24,123 +25,499 @@ Logger writes each request and its response to the access log.
 It gets some information from the logInfoResponseWriter set up by previous middleware.
 */
 type Logger struct {
-	file *os.File
+	writer          *asyncLogWriter
+	options         AccessLogOptions
+	statusRanges    [][2]int
+	frontends       map[string]bool
+	filtered        uint64
+	template        *template.Template
+	requestHeaders  map[string]bool
+	responseHeaders map[string]bool
+	redactedHeaders map[string]bool
+}
+
+// AccessLogOptions holds the format of the access log. The format is common (the default), json,
+// or a text/template executed with a LogEntry. The JSON lines hold the given fields, or all the fields
+// if none is given. The listed request and response headers ("*" for all) are logged, except the values
+// of the redacted ones (by default Authorization, Proxy-Authorization, Cookie and Set-Cookie).
+//
+// Only the requests of the listed frontends, if any, are logged. The requests whose status is in
+// one of the status code ranges, like 500-599, or which last at least MinDuration are always logged,
+// and Sampling is the ratio of the other requests which are logged: 1 by default without status code
+// and duration filters, 0 otherwise. The lines are written asynchronously through a queue of BufferSize
+// lines (1024 by default), dropping the lines when it is full, and flushed every FlushInterval (1s by default).
+type AccessLogOptions struct {
+	Format          string
+	Fields          []string
+	RequestHeaders  []string
+	ResponseHeaders []string
+	RedactedHeaders []string
+	KeepQueryString bool
+	StatusCodes     []string
+	MinDuration     time.Duration
+	Frontends       []string
+	Sampling        float64
+	BufferSize      int
+	FlushInterval   time.Duration
+}
+
+// AccessLogStats holds the number of access log lines written, filtered out, and dropped because
+// the queue of lines was full.
+type AccessLogStats struct {
+	Written  uint64 `json:"written"`
+	Filtered uint64 `json:"filtered"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// LogEntry holds the fields of an access log line. The backend URL is the URL of the server which
+// served the request, and the upstream duration the time spent waiting for it, over all the attempts.
+type LogEntry struct {
+	ClientHost       string
+	ClientUsername   string
+	StartUTC         time.Time
+	RequestMethod    string
+	RequestPath      string
+	RequestProtocol  string
+	RequestReferer   string
+	RequestUserAgent string
+	RequestID        string
+	Status           int
+	Size             int
+	EntryPointName   string
+	FrontendName     string
+	BackendName      string
+	BackendURL       string
+	UpstreamAddr     string
+	Duration         time.Duration
+	UpstreamDuration time.Duration
+	RetryAttempts    int
+	CacheStatus      string
+	TLSVersion       string
+	TLSCipher        string
+	RequestHeaders   map[string]string
+	ResponseHeaders  map[string]string
 }
 
 // Logging handler to log frontend name, backend name, and elapsed time
 type frontendBackendLoggingHandler struct {
-	reqid       string
-	writer      io.Writer
-	handlerFunc http.HandlerFunc
+	reqid          string
+	logger         *Logger
+	entryPointName string
+	handlerFunc    http.HandlerFunc
 }
 
-var (
-	reqidCounter        uint64       // Request ID
-	infoRwMap           = cmap.New() // Map of reqid to response writer
-	backend2FrontendMap *map[string]string
-)
-
 // logInfoResponseWriter is a wrapper of type http.ResponseWriter
-// that tracks frontend and backend names and request status and size
+// that tracks the request status and size
 type logInfoResponseWriter struct {
-	rw       http.ResponseWriter
-	backend  string
-	frontend string
-	status   int
-	size     int
+	rw     http.ResponseWriter
+	status int
+	size   int
 }
 
-// NewLogger returns a new Logger instance.
-func NewLogger(file string) *Logger {
+// NewLogger returns a new Logger writing to file in the given format, or logging nothing if file is empty.
+func NewLogger(file string, options AccessLogOptions) (*Logger, error) {
+	logger := &Logger{
+		options:         options,
+		requestHeaders:  headerNames(options.RequestHeaders),
+		responseHeaders: headerNames(options.ResponseHeaders),
+		redactedHeaders: headerNames(options.RedactedHeaders),
+		frontends:       map[string]bool{},
+	}
+	for _, frontend := range options.Frontends {
+		logger.frontends[frontend] = true
+	}
+	for _, statusCodes := range options.StatusCodes {
+		statusRange, err := parseStatusRange(statusCodes)
+		if err != nil {
+			return nil, err
+		}
+		logger.statusRanges = append(logger.statusRanges, statusRange)
+	}
+	if options.Sampling < 0 || options.Sampling > 1 {
+		return nil, fmt.Errorf("Invalid access log sampling %v, it must be between 0 and 1", options.Sampling)
+	}
+	if options.Sampling == 0 && len(logger.statusRanges) == 0 && options.MinDuration <= 0 {
+		logger.options.Sampling = 1
+	}
+	if len(options.RedactedHeaders) == 0 {
+		logger.redactedHeaders = headerNames([]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"})
+	}
+	switch options.Format {
+	case "", "common":
+	case "json":
+		fields := (&LogEntry{}).fields()
+		for _, field := range options.Fields {
+			if _, ok := fields[field]; !ok {
+				return nil, fmt.Errorf("Unknown access log field %s", field)
+			}
+		}
+	default:
+		accessLogTemplate, err := template.New("accessLog").Parse(options.Format)
+		if err != nil {
+			return nil, err
+		}
+		logger.template = accessLogTemplate
+	}
 	if len(file) > 0 {
 		fi, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
-			log.Fatal("Error opening file", err)
+			return nil, err
+		}
+		bufferSize := options.BufferSize
+		if bufferSize <= 0 {
+			bufferSize = 1024
+		}
+		flushInterval := options.FlushInterval
+		if flushInterval <= 0 {
+			flushInterval = time.Second
 		}
-		return &Logger{fi}
+		logger.writer = newAsyncLogWriter(fi, bufferSize, flushInterval)
 	}
-	return &Logger{nil}
+	return logger, nil
 }
 
-// SetBackend2FrontendMap is called by server.go to set up frontend translation
-func SetBackend2FrontendMap(newMap *map[string]string) {
-	backend2FrontendMap = newMap
+// parseStatusRange parses a status code, like 404, or a range of status codes, like 500-599.
+func parseStatusRange(value string) ([2]int, error) {
+	bounds := strings.SplitN(value, "-", 2)
+	from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return [2]int{}, fmt.Errorf("Invalid status code range %s", value)
+	}
+	to := from
+	if len(bounds) == 2 {
+		if to, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil || to < from {
+			return [2]int{}, fmt.Errorf("Invalid status code range %s", value)
+		}
+	}
+	return [2]int{from, to}, nil
+}
+
+// headerNames returns the set of the canonical names of the headers.
+func headerNames(names []string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		if name != "*" {
+			name = http.CanonicalHeaderKey(name)
+		}
+		set[name] = true
+	}
+	return set
+}
+
+// EntryPointLogger is the negroni handler writing the requests of an entry point to the access log.
+type EntryPointLogger struct {
+	logger         *Logger
+	entryPointName string
+}
+
+// ForEntryPoint returns the handler logging the requests of the entry point named entryPointName.
+func (l *Logger) ForEntryPoint(entryPointName string) *EntryPointLogger {
+	return &EntryPointLogger{logger: l, entryPointName: entryPointName}
+}
+
+func (epl *EntryPointLogger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	epl.logger.serveHTTP(rw, r, next, epl.entryPointName)
 }
 
 func (l *Logger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	if l.file == nil {
+	l.serveHTTP(rw, r, next, "")
+}
+
+func (l *Logger) serveHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc, entryPointName string) {
+	if l.writer == nil {
 		next(rw, r)
 	} else {
-		reqid := strconv.FormatUint(atomic.AddUint64(&reqidCounter, 1), 10)
-		r.Header[loggerReqidHeader] = []string{reqid}
-		defer deleteReqid(r, reqid)
-		frontendBackendLoggingHandler{reqid, l.file, next}.ServeHTTP(rw, r)
+		_, requestID := getRequestID(r)
+		frontendBackendLoggingHandler{requestID, l, entryPointName, next}.ServeHTTP(rw, r)
 	}
 }
 
-// Delete a reqid from the map and the request's headers
-func deleteReqid(r *http.Request, reqid string) {
-	infoRwMap.Remove(reqid)
-	delete(r.Header, loggerReqidHeader)
+// Save the time spent waiting for the backend for the Logger
+func saveUpstreamDurationForLogger(r *http.Request, duration time.Duration) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.upstreamDuration += duration
+		info.mutex.Unlock()
+	}
 }
 
-// Save the backend name for the Logger
-func saveBackendNameForLogger(r *http.Request, backendName string) {
-	if reqidHdr := r.Header[loggerReqidHeader]; len(reqidHdr) == 1 {
-		reqid := reqidHdr[0]
-		if infoRw, ok := infoRwMap.Get(reqid); ok {
-			infoRw.(*logInfoResponseWriter).SetBackend(backendName)
-			infoRw.(*logInfoResponseWriter).SetFrontend((*backend2FrontendMap)[backendName])
-		}
+// Save the authenticated user name for the Logger
+func saveUsernameForLogger(r *http.Request, username string) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.username = username
+		info.mutex.Unlock()
+	}
+}
+
+// Save the cache status of the response for the Logger
+func saveCacheStatusForLogger(r *http.Request, cacheStatus string) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.cacheStatus = cacheStatus
+		info.mutex.Unlock()
+	}
+}
+
+// getLoggerInfo returns what was saved for the Logger about the request.
+func getLoggerInfo(r *http.Request) (username string, cacheStatus string, upstreamDuration time.Duration) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		defer info.mutex.Unlock()
+		return info.username, info.cacheStatus, info.upstreamDuration
 	}
+	return "", "", 0
 }
 
 // Close closes the Logger (i.e. the file).
 func (l *Logger) Close() {
-	if l.file != nil {
-		l.file.Close()
+	if l.writer != nil {
+		l.writer.close()
+	}
+}
+
+// Reopen reopens the access log file, once moved by a log rotation.
+func (l *Logger) Reopen() error {
+	if l.writer == nil {
+		return nil
 	}
+	return l.writer.reopen()
+}
+
+// OnDrop sets the function called for each line dropped because the writes to the file were too slow,
+// for the metrics.
+func (l *Logger) OnDrop(onDrop func()) {
+	if l.writer != nil {
+		l.writer.onDrop = onDrop
+	}
+}
+
+// Stats returns the statistics of the access log.
+func (l *Logger) Stats() AccessLogStats {
+	stats := AccessLogStats{Filtered: atomic.LoadUint64(&l.filtered)}
+	if l.writer != nil {
+		stats.Written = atomic.LoadUint64(&l.writer.written)
+		stats.Dropped = atomic.LoadUint64(&l.writer.dropped)
+	}
+	return stats
+}
+
+// logged returns true if the request of the entry must be written to the access log.
+func (l *Logger) logged(entry *LogEntry, frontend string) bool {
+	if len(l.frontends) > 0 && !l.frontends[frontend] && !l.frontends[entry.FrontendName] {
+		return false
+	}
+	for _, statusRange := range l.statusRanges {
+		if entry.Status >= statusRange[0] && entry.Status <= statusRange[1] {
+			return true
+		}
+	}
+	if l.options.MinDuration > 0 && entry.Duration >= l.options.MinDuration {
+		return true
+	}
+	return l.options.Sampling >= 1 || (l.options.Sampling > 0 && rand.Float64() < l.options.Sampling)
 }
 
 // Logging handler to log frontend name, backend name, and elapsed time
 func (fblh frontendBackendLoggingHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	startTime := time.Now()
 	infoRw := &logInfoResponseWriter{rw: rw}
-	infoRwMap.Set(fblh.reqid, infoRw)
 	fblh.handlerFunc(infoRw, req)
+	handledBy := getHandledBy(req)
+	username, cacheStatus, upstreamDuration := getLoggerInfo(req)
 
-	username := "-"
+	entry := &LogEntry{
+		ClientHost:       "-",
+		ClientUsername:   "-",
+		StartUTC:         startTime.UTC(),
+		RequestMethod:    req.Method,
+		RequestProtocol:  req.Proto,
+		RequestReferer:   req.Referer(),
+		RequestUserAgent: req.UserAgent(),
+		RequestID:        fblh.reqid,
+		Status:           infoRw.GetStatus(),
+		Size:             infoRw.GetSize(),
+		EntryPointName:   fblh.entryPointName,
+		FrontendName:     strings.TrimPrefix(handledBy.frontend, "frontend-"),
+		BackendName:      strings.TrimPrefix(handledBy.backend, "backend-"),
+		BackendURL:       handledBy.server,
+		Duration:         time.Now().UTC().Sub(startTime.UTC()),
+		UpstreamDuration: upstreamDuration,
+		CacheStatus:      cacheStatus,
+	}
+	if !fblh.logger.logged(entry, handledBy.frontend) {
+		atomic.AddUint64(&fblh.logger.filtered, 1)
+		return
+	}
 	url := *req.URL
-	if url.User != nil {
+	if username != "" {
+		entry.ClientUsername = username
+	} else if url.User != nil {
 		if name := url.User.Username(); name != "" {
-			username = name
+			entry.ClientUsername = name
 		}
 	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		entry.ClientHost = host
+	} else if len(req.RemoteAddr) > 0 {
+		entry.ClientHost = req.RemoteAddr
+	}
+	entry.RequestPath = url.RequestURI()
+	if qmIndex := strings.Index(entry.RequestPath, "?"); qmIndex > 0 && !fblh.logger.options.KeepQueryString {
+		entry.RequestPath = entry.RequestPath[0:qmIndex]
+	}
+	if backendURL, err := neturl.Parse(entry.BackendURL); err == nil {
+		entry.UpstreamAddr = backendURL.Host
+	}
+	if handledBy.attempts > 1 {
+		entry.RetryAttempts = handledBy.attempts - 1
+	}
+	if req.TLS != nil {
+		entry.TLSVersion = tlsVersionName(req.TLS.Version)
+		entry.TLSCipher = tlsCipherName(req.TLS.CipherSuite)
+	}
+	entry.RequestHeaders = fblh.logger.loggedHeaders(req.Header, fblh.logger.requestHeaders)
+	entry.ResponseHeaders = fblh.logger.loggedHeaders(infoRw.Header(), fblh.logger.responseHeaders)
 
-	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	line, err := fblh.logger.format(entry)
 	if err != nil {
-		host = req.RemoteAddr
+		log.Errorf("Error formatting access log line of request %s: %s", fblh.reqid, err)
+		return
+	}
+	fblh.logger.writer.writeLine(line)
+}
+
+// format returns the access log line of the entry, ending with a new line.
+func (l *Logger) format(entry *LogEntry) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	switch {
+	case l.template != nil:
+		if err := l.template.Execute(buffer, entry); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(buffer.Bytes(), []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+	case l.options.Format == "json":
+		fields := entry.fields()
+		if len(entry.RequestHeaders) == 0 {
+			delete(fields, "requestHeaders")
+		}
+		if len(entry.ResponseHeaders) == 0 {
+			delete(fields, "responseHeaders")
+		}
+		if len(l.options.Fields) > 0 {
+			selectedFields := map[string]interface{}{}
+			for _, field := range l.options.Fields {
+				if value, ok := fields[field]; ok {
+					selectedFields[field] = value
+				}
+			}
+			fields = selectedFields
+		}
+		if err := json.NewEncoder(buffer).Encode(fields); err != nil {
+			return nil, err
+		}
+	default:
+		fmt.Fprintf(buffer, `%s - %s [%s] "%s %s %s" %d %d "%s" "%s" %s "%s" "%s" %s%s`,
+			entry.ClientHost, entry.ClientUsername, entry.StartUTC.Local().Format("02/Jan/2006:15:04:05 -0700"),
+			entry.RequestMethod, entry.RequestPath, entry.RequestProtocol, entry.Status, entry.Size,
+			entry.RequestReferer, entry.RequestUserAgent, entry.RequestID, entry.FrontendName, entry.BackendURL,
+			entry.Duration, "\n")
 	}
+	return buffer.Bytes(), nil
+}
 
-	ts := startTime.Format("02/Jan/2006:15:04:05 -0700")
-	method := req.Method
-	uri := url.RequestURI()
-	if qmIndex := strings.Index(uri, "?"); qmIndex > 0 {
-		uri = uri[0:qmIndex]
+// fields returns the JSON fields of the entry, with the durations in nanoseconds.
+func (entry *LogEntry) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"clientHost":       entry.ClientHost,
+		"clientUsername":   entry.ClientUsername,
+		"startUTC":         entry.StartUTC,
+		"requestMethod":    entry.RequestMethod,
+		"requestPath":      entry.RequestPath,
+		"requestProtocol":  entry.RequestProtocol,
+		"requestReferer":   entry.RequestReferer,
+		"requestUserAgent": entry.RequestUserAgent,
+		"requestID":        entry.RequestID,
+		"status":           entry.Status,
+		"size":             entry.Size,
+		"entryPointName":   entry.EntryPointName,
+		"frontendName":     entry.FrontendName,
+		"backendName":      entry.BackendName,
+		"backendURL":       entry.BackendURL,
+		"upstreamAddr":     entry.UpstreamAddr,
+		"duration":         int64(entry.Duration),
+		"upstreamDuration": int64(entry.UpstreamDuration),
+		"retryAttempts":    entry.RetryAttempts,
+		"cacheStatus":      entry.CacheStatus,
+		"tlsVersion":       entry.TLSVersion,
+		"tlsCipher":        entry.TLSCipher,
+		"requestHeaders":   entry.RequestHeaders,
+		"responseHeaders":  entry.ResponseHeaders,
 	}
-	proto := req.Proto
-	referer := req.Referer()
-	agent := req.UserAgent()
+}
 
-	frontend := strings.TrimPrefix(infoRw.GetFrontend(), "frontend-")
-	backend := infoRw.GetBackend()
-	status := infoRw.GetStatus()
-	size := infoRw.GetSize()
+// loggedHeaders returns the values of the logged headers, redacting the sensitive ones.
+func (l *Logger) loggedHeaders(header http.Header, names map[string]bool) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	headers := map[string]string{}
+	for name, values := range header {
+		if !names["*"] && !names[name] {
+			continue
+		}
+		if l.redactedHeaders[name] {
+			headers[name] = "REDACTED"
+		} else {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
 
-	elapsed := time.Now().UTC().Sub(startTime.UTC())
-	fmt.Fprintf(fblh.writer, `%s - %s [%s] "%s %s %s" %d %d "%s" "%s" %s "%s" "%s" %s%s`,
-		host, username, ts, method, uri, proto, status, size, referer, agent, fblh.reqid, frontend, backend, elapsed, "\n")
+// tlsVersionName returns the name of a TLS version, like 1.2.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionSSL30:
+		return "SSL3.0"
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+var tlsCipherNames = map[uint16]string{
+	tls.TLS_RSA_WITH_RC4_128_SHA:                "TLS_RSA_WITH_RC4_128_SHA",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:         "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:         "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:        "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:          "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+}
 
+// tlsCipherName returns the name of a TLS cipher suite.
+func tlsCipherName(cipherSuite uint16) string {
+	if name, ok := tlsCipherNames[cipherSuite]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", cipherSuite)
 }
 
 func (lirw *logInfoResponseWriter) Header() http.Header {
@@ -179,19 +556,3 @@ func (lirw *logInfoResponseWriter) GetStatus() int {
 func (lirw *logInfoResponseWriter) GetSize() int {
 	return lirw.size
 }
-
-func (lirw *logInfoResponseWriter) GetBackend() string {
-	return lirw.backend
-}
-
-func (lirw *logInfoResponseWriter) GetFrontend() string {
-	return lirw.frontend
-}
-
-func (lirw *logInfoResponseWriter) SetBackend(backend string) {
-	lirw.backend = backend
-}
-
-func (lirw *logInfoResponseWriter) SetFrontend(frontend string) {
-	lirw.frontend = frontend
-}
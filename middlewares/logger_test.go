@@ -1,40 +1,43 @@
 package middlewares
 
 import (
+	"encoding/json"
 	"fmt"
 	shellwords "github.com/mattn/go-shellwords"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 type logtestResponseWriter struct{}
 
 var (
-	logger                  *Logger
-	logfileName             = "traefikTestLogger.log"
-	logfilePath             string
-	helloWorld              = "Hello, World"
-	testBackendName         = "http://127.0.0.1/testBackend"
-	testFrontendName        = "testFrontend"
-	testStatus              = 123
-	testHostname            = "TestHost"
-	testUsername            = "TestUser"
-	testPath                = "http://testpath"
-	testPort                = 8181
-	testProto               = "HTTP/0.0"
-	testMethod              = "POST"
-	testReferer             = "testReferer"
-	testUserAgent           = "testUserAgent"
-	testBackend2FrontendMap = map[string]string{
-		testBackendName: testFrontendName,
-	}
-	printedLogdata bool
+	logger           *Logger
+	logfileName      = "traefikTestLogger.log"
+	logfilePath      string
+	helloWorld       = "Hello, World"
+	testBackendURL   = "http://127.0.0.1/testBackend"
+	testBackendName  = "testBackend"
+	testFrontendName = "testFrontend"
+	testStatus       = 123
+	testHostname     = "TestHost"
+	testUsername     = "TestUser"
+	testPath         = "http://testpath"
+	testPort         = 8181
+	testProto        = "HTTP/0.0"
+	testMethod       = "POST"
+	testReferer      = "testReferer"
+	testRequestID    = "7b0e1f6c-1a4e-4d5b-9c3e-2f8a6d4b1c0e"
+	testUserAgent    = "testUserAgent"
+	printedLogdata   bool
 )
 
 func TestLogger(t *testing.T) {
@@ -44,14 +47,16 @@ func TestLogger(t *testing.T) {
 		logfilePath = filepath.Join("/tmp", logfileName)
 	}
 
-	logger = NewLogger(logfilePath)
+	var err error
+	logger, err = NewLogger(logfilePath, AccessLogOptions{})
+	assert.NoError(t, err)
 	defer cleanup()
-	SetBackend2FrontendMap(&testBackend2FrontendMap)
 
 	r := &http.Request{
 		Header: map[string][]string{
-			"User-Agent": {testUserAgent},
-			"Referer":    {testReferer},
+			"User-Agent":   {testUserAgent},
+			"Referer":      {testReferer},
+			"X-Request-Id": {testRequestID},
 		},
 		Proto:      testProto,
 		Host:       testHostname,
@@ -63,7 +68,9 @@ func TestLogger(t *testing.T) {
 		},
 	}
 
-	logger.ServeHTTP(&logtestResponseWriter{}, r, LogWriterTestHandlerFunc)
+	serveWithRequestInfo(logger, &logtestResponseWriter{}, r, LogWriterTestHandlerFunc)
+	// the lines are written when the logger is closed
+	logger.Close()
 
 	if logdata, err := ioutil.ReadFile(logfilePath); err != nil {
 		fmt.Printf("%s\n%s\n", string(logdata), err.Error())
@@ -79,12 +86,160 @@ func TestLogger(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("%d", len(helloWorld)), tokens[7], printLogdata(logdata))
 		assert.Equal(t, testReferer, tokens[8], printLogdata(logdata))
 		assert.Equal(t, testUserAgent, tokens[9], printLogdata(logdata))
-		assert.Equal(t, "1", tokens[10], printLogdata(logdata))
+		assert.Equal(t, testRequestID, tokens[10], printLogdata(logdata))
 		assert.Equal(t, testFrontendName, tokens[11], printLogdata(logdata))
-		assert.Equal(t, testBackendName, tokens[12], printLogdata(logdata))
+		assert.Equal(t, testBackendURL, tokens[12], printLogdata(logdata))
 	}
 }
 
+func TestLoggerJSON(t *testing.T) {
+	logfile, err := ioutil.TempFile("", "traefik-access-log")
+	assert.NoError(t, err)
+	logfile.Close()
+	defer os.Remove(logfile.Name())
+
+	jsonLogger, err := NewLogger(logfile.Name(), AccessLogOptions{
+		Format:          "json",
+		Fields:          []string{"clientHost", "requestPath", "status", "entryPointName", "frontendName", "backendName", "upstreamAddr", "retryAttempts", "requestHeaders"},
+		RequestHeaders:  []string{"Authorization", "X-Custom"},
+		KeepQueryString: true,
+	})
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://test.localhost/foo?bar=1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Authorization", "Basic secret")
+	req.Header.Set("X-Custom", "value")
+	serveWithRequestInfo(jsonLogger.ForEntryPoint("http"), &logtestResponseWriter{}, req, func(rw http.ResponseWriter, r *http.Request) {
+		// the request is retried once
+		NewSaveBackend(http.NotFoundHandler(), testBackendName).ServeHTTP(httptest.NewRecorder(), r)
+		LogWriterTestHandlerFunc(rw, r)
+	})
+	jsonLogger.Close()
+
+	logdata, err := ioutil.ReadFile(logfile.Name())
+	assert.NoError(t, err)
+	fields := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(logdata, &fields), string(logdata))
+	assert.Equal(t, map[string]interface{}{
+		"clientHost":     "10.0.0.1",
+		"requestPath":    "/foo?bar=1",
+		"status":         float64(testStatus),
+		"entryPointName": "http",
+		"frontendName":   testFrontendName,
+		"backendName":    testBackendName,
+		"upstreamAddr":   "127.0.0.1",
+		"retryAttempts":  float64(1),
+		"requestHeaders": map[string]interface{}{"Authorization": "REDACTED", "X-Custom": "value"},
+	}, fields)
+
+	_, err = NewLogger("", AccessLogOptions{Format: "json", Fields: []string{"unknown"}})
+	assert.Error(t, err)
+}
+
+func TestLoggerTemplate(t *testing.T) {
+	logfile, err := ioutil.TempFile("", "traefik-access-log")
+	assert.NoError(t, err)
+	logfile.Close()
+	defer os.Remove(logfile.Name())
+
+	templateLogger, err := NewLogger(logfile.Name(), AccessLogOptions{Format: "{{.RequestMethod}} {{.RequestPath}} {{.Status}}"})
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://test.localhost/foo?bar=1", nil)
+	templateLogger.ServeHTTP(&logtestResponseWriter{}, req, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	templateLogger.Close()
+
+	logdata, err := ioutil.ReadFile(logfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "GET /foo 204\n", string(logdata))
+}
+
+func TestLoggerFilters(t *testing.T) {
+	logfile, err := ioutil.TempFile("", "traefik-access-log")
+	assert.NoError(t, err)
+	logfile.Close()
+	defer os.Remove(logfile.Name())
+
+	filterLogger, err := NewLogger(logfile.Name(), AccessLogOptions{
+		Format:      "{{.RequestPath}}",
+		StatusCodes: []string{"404", "500-599"},
+		MinDuration: 50 * time.Millisecond,
+		Frontends:   []string{testFrontendName},
+	})
+	assert.NoError(t, err)
+	serve := func(path string, status int, duration time.Duration, frontendName string) {
+		req, _ := http.NewRequest("GET", "http://test.localhost"+path, nil)
+		serveWithRequestInfo(filterLogger, &logtestResponseWriter{}, req, func(rw http.ResponseWriter, r *http.Request) {
+			saveFrontend(r, frontendName)
+			time.Sleep(duration)
+			rw.WriteHeader(status)
+		})
+	}
+	serve("/ok", http.StatusOK, 0, testFrontendName)
+	serve("/not-found", http.StatusNotFound, 0, testFrontendName)
+	serve("/error", http.StatusBadGateway, 0, testFrontendName)
+	serve("/slow", http.StatusOK, 60*time.Millisecond, testFrontendName)
+	serve("/other-frontend", http.StatusBadGateway, 0, "otherFrontend")
+	filterLogger.Close()
+
+	logdata, err := ioutil.ReadFile(logfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "/not-found\n/error\n/slow\n", string(logdata))
+	assert.Equal(t, AccessLogStats{Written: 3, Filtered: 2}, filterLogger.Stats())
+
+	_, err = NewLogger("", AccessLogOptions{StatusCodes: []string{"599-500"}})
+	assert.Error(t, err)
+}
+
+func TestAsyncLogWriter(t *testing.T) {
+	logfile, err := ioutil.TempFile("", "traefik-access-log")
+	assert.NoError(t, err)
+	defer os.Remove(logfile.Name())
+
+	writer := newAsyncLogWriter(logfile, 1, time.Millisecond)
+	var onDrop uint64
+	writer.onDrop = func() { onDrop++ }
+	for i := 0; i < 100; i++ {
+		writer.writeLine([]byte("line\n"))
+	}
+	assert.NoError(t, writer.close())
+	writer.writeLine([]byte("line\n"))
+
+	logdata, err := ioutil.ReadFile(logfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, int(writer.written), strings.Count(string(logdata), "line\n"))
+	assert.Equal(t, uint64(101), writer.written+writer.dropped)
+	assert.Equal(t, writer.dropped, onDrop)
+}
+
+func TestLoggerReopen(t *testing.T) {
+	directory, err := ioutil.TempDir("", "traefik-access-log")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	logfilePath := filepath.Join(directory, "access.log")
+
+	reopenLogger, err := NewLogger(logfilePath, AccessLogOptions{Format: "{{.RequestPath}}"})
+	assert.NoError(t, err)
+	serve := func(path string) {
+		req, _ := http.NewRequest("GET", "http://test.localhost"+path, nil)
+		reopenLogger.ServeHTTP(&logtestResponseWriter{}, req, func(rw http.ResponseWriter, r *http.Request) {})
+	}
+	serve("/before")
+	// the lines queued before the rotation are written to the rotated file
+	assert.NoError(t, os.Rename(logfilePath, logfilePath+".1"))
+	assert.NoError(t, reopenLogger.Reopen())
+	serve("/after")
+	reopenLogger.Close()
+
+	rotated, err := ioutil.ReadFile(logfilePath + ".1")
+	assert.NoError(t, err)
+	assert.Equal(t, "/before\n", string(rotated))
+	logdata, err := ioutil.ReadFile(logfilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "/after\n", string(logdata))
+}
+
 func cleanup() {
 	logger.Close()
 	os.Remove(logfilePath)
@@ -94,14 +249,24 @@ func printLogdata(logdata []byte) string {
 	return fmt.Sprintf(
 		"\nExpected: %s\n"+
 			"Actual:   %s",
-		"TestHost - TestUser [13/Apr/2016:07:14:19 -0700] \"POST http://testpath HTTP/0.0\" 123 12 \"testReferer\" \"testUserAgent\" 1 \"testFrontend\" \"http://127.0.0.1/testBackend\" 1ms",
+		"TestHost - TestUser [13/Apr/2016:07:14:19 -0700] \"POST http://testpath HTTP/0.0\" 123 12 \"testReferer\" \"testUserAgent\" 7b0e1f6c-1a4e-4d5b-9c3e-2f8a6d4b1c0e \"testFrontend\" \"http://127.0.0.1/testBackend\" 1ms",
 		string(logdata))
 }
 
 func LogWriterTestHandlerFunc(rw http.ResponseWriter, r *http.Request) {
 	rw.Write([]byte(helloWorld))
 	rw.WriteHeader(testStatus)
-	saveBackendNameForLogger(r, testBackendName)
+	saveFrontend(r, testFrontendName)
+	saveServer(r, testBackendName, testBackendURL)
+}
+
+// serveWithRequestInfo serves the request through the logger, with the request info and ID kept by the entry points.
+func serveWithRequestInfo(l interface {
+	ServeHTTP(http.ResponseWriter, *http.Request, http.HandlerFunc)
+}, rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	serveWithRequestID(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+		l.ServeHTTP(rw, r, next)
+	})
 }
 
 func (lrw *logtestResponseWriter) Header() http.Header {
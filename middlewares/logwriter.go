@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bufio"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// asyncLogWriter writes the access log lines to a file from its own goroutine, so that the requests
+// never wait for the disk. The lines go through a bounded queue, and are dropped when it is full.
+// They are buffered and flushed to the file every flushInterval.
+type asyncLogWriter struct {
+	file          *os.File
+	lines         chan []byte
+	reopens       chan chan error
+	flushInterval time.Duration
+	mutex         sync.RWMutex
+	closed        bool
+	done          chan struct{}
+	written       uint64
+	dropped       uint64
+	onDrop        func()
+}
+
+func newAsyncLogWriter(file *os.File, bufferSize int, flushInterval time.Duration) *asyncLogWriter {
+	writer := &asyncLogWriter{
+		file:          file,
+		lines:         make(chan []byte, bufferSize),
+		reopens:       make(chan chan error),
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go writer.run()
+	return writer
+}
+
+// writeLine queues a line, or drops it if the queue is full.
+func (w *asyncLogWriter) writeLine(line []byte) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.closed {
+		w.drop()
+		return
+	}
+	select {
+	case w.lines <- line:
+	default:
+		w.drop()
+	}
+}
+
+func (w *asyncLogWriter) drop() {
+	atomic.AddUint64(&w.dropped, 1)
+	if w.onDrop != nil {
+		w.onDrop()
+	}
+}
+
+func (w *asyncLogWriter) run() {
+	defer close(w.done)
+	buffer := bufio.NewWriter(w.file)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	flush := func() {
+		if err := buffer.Flush(); err != nil {
+			log.Errorf("Error writing access log: %s", err)
+			buffer.Reset(w.file)
+		}
+	}
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				flush()
+				return
+			}
+			buffer.Write(line)
+			atomic.AddUint64(&w.written, 1)
+		case <-ticker.C:
+			flush()
+		case result := <-w.reopens:
+			// the lines queued before the rotation go to the rotated file
+			for drained := false; !drained; {
+				select {
+				case line, ok := <-w.lines:
+					if ok {
+						buffer.Write(line)
+						atomic.AddUint64(&w.written, 1)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
+				}
+			}
+			flush()
+			file, err := os.OpenFile(w.file.Name(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+			if err == nil {
+				w.file.Close()
+				w.file = file
+				buffer.Reset(file)
+			}
+			result <- err
+		}
+	}
+}
+
+// reopen writes the buffered lines and reopens the file, once moved by a log rotation.
+func (w *asyncLogWriter) reopen() error {
+	w.mutex.RLock()
+	if w.closed {
+		w.mutex.RUnlock()
+		return nil
+	}
+	result := make(chan error, 1)
+	w.reopens <- result
+	w.mutex.RUnlock()
+	return <-result
+}
+
+// close writes the queued lines and closes the file.
+func (w *asyncLogWriter) close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.lines)
+	w.mutex.Unlock()
+	<-w.done
+	return w.file.Close()
+}
@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Maintenance answers the requests with a 503 status while the frontend is in maintenance,
+// except the requests coming from the allowed source ranges, which are forwarded to next.
+// The maintenance is enabled by the configuration and can be toggled at runtime.
+type Maintenance struct {
+	next         http.Handler
+	page         []byte
+	contentType  string
+	retryAfter   string
+	sourceRanges []*net.IPNet
+	mutex        sync.RWMutex
+	configured   bool
+	enabled      bool
+	overridden   bool
+}
+
+// NewMaintenance returns a new Maintenance enabled if configured is true. The requests are answered
+// with page, or with the status text if page is empty, and with a Retry-After header set to retryAfter,
+// in seconds or as an HTTP date. The source ranges are IPs or CIDRs.
+func NewMaintenance(next http.Handler, configured bool, page []byte, retryAfter string, sourceRanges []string) (*Maintenance, error) {
+	maintenance := &Maintenance{
+		next:        next,
+		page:        page,
+		contentType: "text/plain; charset=utf-8",
+		retryAfter:  retryAfter,
+		configured:  configured,
+		enabled:     configured,
+	}
+	if len(page) == 0 {
+		maintenance.page = []byte(http.StatusText(http.StatusServiceUnavailable))
+	} else {
+		maintenance.contentType = http.DetectContentType(page)
+	}
+	ipNets, err := parseSourceRanges(sourceRanges)
+	if err != nil {
+		return nil, err
+	}
+	maintenance.sourceRanges = ipNets
+	return maintenance, nil
+}
+
+// Enabled returns true if the frontend is in maintenance.
+func (m *Maintenance) Enabled() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.enabled
+}
+
+// SetEnabled enables or disables the maintenance at runtime.
+func (m *Maintenance) SetEnabled(enabled bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.enabled = enabled
+	m.overridden = true
+}
+
+// Inherit keeps the maintenance state set at runtime on a previous Maintenance of the same
+// frontend, as long as the configured state did not change.
+func (m *Maintenance) Inherit(previous *Maintenance) {
+	previous.mutex.RLock()
+	defer previous.mutex.RUnlock()
+	if !previous.overridden || previous.configured != m.configured {
+		return
+	}
+	m.SetEnabled(previous.enabled)
+}
+
+func (m *Maintenance) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !m.Enabled() || m.allowed(r) {
+		m.next.ServeHTTP(rw, r)
+		return
+	}
+	rw.Header().Set("Content-Type", m.contentType)
+	if len(m.retryAfter) > 0 {
+		rw.Header().Set("Retry-After", m.retryAfter)
+	}
+	rw.WriteHeader(http.StatusServiceUnavailable)
+	if r.Method != "HEAD" {
+		rw.Write(m.page)
+	}
+}
+
+// allowed returns true if the request comes from an allowed source range.
+func (m *Maintenance) allowed(r *http.Request) bool {
+	if len(m.sourceRanges) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return containsIP(m.sourceRanges, ip)
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenance(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("backend"))
+	})
+	maintenance, err := NewMaintenance(handler, true, []byte("<html>Back soon</html>"), "120", []string{"10.0.0.0/8", "192.168.1.2"})
+	assert.NoError(t, err)
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		maintenance.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve("192.168.1.3:1234")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "120", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "<html>Back soon</html>", recorder.Body.String())
+	assert.Equal(t, "backend", serve("10.1.2.3:1234").Body.String())
+	assert.Equal(t, "backend", serve("192.168.1.2:1234").Body.String())
+
+	maintenance.SetEnabled(false)
+	assert.Equal(t, "backend", serve("192.168.1.3:1234").Body.String())
+
+	// the runtime state is kept as long as the configuration does not change
+	next, err := NewMaintenance(handler, true, nil, "", nil)
+	assert.NoError(t, err)
+	next.Inherit(maintenance)
+	assert.False(t, next.Enabled())
+	next, err = NewMaintenance(handler, false, nil, "", nil)
+	assert.NoError(t, err)
+	maintenance.SetEnabled(true)
+	next.Inherit(maintenance)
+	assert.False(t, next.Enabled())
+
+	_, err = NewMaintenance(handler, true, nil, "", []string{"10.0.0.300"})
+	assert.Error(t, err)
+}
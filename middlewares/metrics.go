@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/oxy/cbreaker"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Metrics holds the metrics recorded for the entry points, frontends and backends.
+type Metrics struct {
+	// Requests counts the requests by entrypoint, frontend, backend, method and code.
+	Requests metrics.Counter
+	// RequestDurations observes the duration of the requests in seconds, with the same labels as Requests.
+	RequestDurations metrics.Histogram
+	// OpenConnections is the number of connections open on each entrypoint.
+	OpenConnections metrics.Gauge
+	// Retries counts the requests retried on another server, by backend.
+	Retries metrics.Counter
+	// CircuitBreakerTrips counts the trips of the circuit breakers, by backend.
+	CircuitBreakerTrips metrics.Counter
+	// ConfigReloads counts the configuration reloads, by status (success or failure).
+	ConfigReloads metrics.Counter
+	// LastConfigReload is the Unix timestamp of the last configuration reload, by status.
+	LastConfigReload metrics.Gauge
+	// ServerUp is 1 if a server of a backend is up, 0 if it is ejected or removed, by backend and url.
+	ServerUp metrics.Gauge
+	// CacheRequests counts the requests of the frontends with a response cache, by frontend and
+	// cache status (hit, miss, stale, revalidated or bypass).
+	CacheRequests metrics.Counter
+	// AccessLogDroppedLines counts the access log lines dropped because the writes to the file were too slow.
+	AccessLogDroppedLines metrics.Counter
+
+	mutex       sync.Mutex
+	connections map[string]*int64
+	stops       []func()
+}
+
+// EntryPointMetrics is the negroni handler recording the requests of an entry point, labelled
+// with the frontend and backend which handled them according to RequestInfo.
+type EntryPointMetrics struct {
+	metrics        *Metrics
+	entryPointName string
+}
+
+// ForEntryPoint returns the handler recording the requests of the entry point named entryPointName.
+func (m *Metrics) ForEntryPoint(entryPointName string) *EntryPointMetrics {
+	return &EntryPointMetrics{metrics: m, entryPointName: entryPointName}
+}
+
+func (epm *EntryPointMetrics) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	statusRw := &statusResponseWriter{ResponseWriter: rw}
+	next(statusRw, r)
+	if statusRw.status == 0 {
+		statusRw.status = http.StatusOK
+	}
+	handledBy := getHandledBy(r)
+	labelValues := []string{
+		"entrypoint", epm.entryPointName,
+		"frontend", handledBy.frontend,
+		"backend", handledBy.backend,
+		"method", r.Method,
+		"code", strconv.Itoa(statusRw.status),
+	}
+	epm.metrics.Requests.With(labelValues...).Add(1)
+	epm.metrics.RequestDurations.With(labelValues...).Observe(time.Since(start).Seconds())
+}
+
+// ConnStateHook returns the http.Server ConnState hook counting the open connections of the entry point.
+func (m *Metrics) ConnStateHook(entryPointName string) func(net.Conn, http.ConnState) {
+	m.mutex.Lock()
+	if m.connections == nil {
+		m.connections = map[string]*int64{}
+	}
+	// a reloaded entry point keeps counting the connections of the previous server
+	connections, ok := m.connections[entryPointName]
+	if !ok {
+		connections = new(int64)
+		m.connections[entryPointName] = connections
+	}
+	m.mutex.Unlock()
+	gauge := m.OpenConnections.With("entrypoint", entryPointName)
+	return func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			gauge.Set(float64(atomic.AddInt64(connections, 1)))
+		case http.StateHijacked, http.StateClosed:
+			gauge.Set(float64(atomic.AddInt64(connections, -1)))
+		}
+	}
+}
+
+// BackendMetrics counts the retries of the requests served by next.
+type BackendMetrics struct {
+	next    http.Handler
+	retries metrics.Counter
+}
+
+// NewBackendMetrics returns a new BackendMetrics for the backend named backendName.
+func (m *Metrics) NewBackendMetrics(next http.Handler, backendName string) *BackendMetrics {
+	return &BackendMetrics{next: next, retries: m.Retries.With("backend", backendName)}
+}
+
+func (bm *BackendMetrics) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	attempts := getHandledBy(r).attempts
+	bm.next.ServeHTTP(rw, r)
+	attempts = getHandledBy(r).attempts - attempts
+	if attempts > 1 {
+		bm.retries.Add(float64(attempts - 1))
+	}
+}
+
+// CircuitBreakerTripped returns the circuit breaker side effect counting the trips of the backend.
+func (m *Metrics) CircuitBreakerTripped(backendName string) cbreaker.SideEffect {
+	return circuitBreakerTrips{m.CircuitBreakerTrips.With("backend", backendName)}
+}
+
+type circuitBreakerTrips struct {
+	counter metrics.Counter
+}
+
+func (cbt circuitBreakerTrips) Exec() error {
+	cbt.counter.Add(1)
+	return nil
+}
+
+// ConfigReloaded records a configuration reload, successful or not.
+func (m *Metrics) ConfigReloaded(success bool) {
+	status := "success"
+	if !success {
+		status = "failure"
+	}
+	m.ConfigReloads.With("status", status).Add(1)
+	m.LastConfigReload.With("status", status).Set(float64(time.Now().Unix()))
+}
+
+// SetServerUp records whether the server at serverURL of the backend is up.
+func (m *Metrics) SetServerUp(backendName string, serverURL string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	m.ServerUp.With("backend", backendName, "url", serverURL).Set(value)
+}
+
+// AccessLogLineDropped counts an access log line dropped.
+func (m *Metrics) AccessLogLineDropped() {
+	m.AccessLogDroppedLines.Add(1)
+}
+
+// CacheRequested returns the function counting the requests of the response cache of the frontend,
+// by cache status.
+func (m *Metrics) CacheRequested(frontendName string) func(cacheStatus string) {
+	counter := m.CacheRequests.With("frontend", frontendName)
+	return func(cacheStatus string) {
+		counter.With("status", strings.ToLower(cacheStatus)).Add(1)
+	}
+}
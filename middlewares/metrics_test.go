@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMetric records the values of a metric by label values.
+type testMetric struct {
+	mutex       *sync.Mutex
+	values      map[string]float64
+	labelValues []string
+}
+
+func newTestMetric() *testMetric {
+	return &testMetric{mutex: &sync.Mutex{}, values: map[string]float64{}}
+}
+
+func (tm *testMetric) with(labelValues ...string) *testMetric {
+	return &testMetric{mutex: tm.mutex, values: tm.values, labelValues: append(append([]string(nil), tm.labelValues...), labelValues...)}
+}
+
+func (tm *testMetric) With(labelValues ...string) metrics.Counter {
+	return tm.with(labelValues...)
+}
+
+func (tm *testMetric) Add(delta float64) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.values[strings.Join(tm.labelValues, ",")] += delta
+}
+
+func (tm *testMetric) Set(value float64) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.values[strings.Join(tm.labelValues, ",")] = value
+}
+
+func (tm *testMetric) Observe(value float64) {
+	tm.Add(1)
+}
+
+type testGauge struct{ *testMetric }
+
+func (tg testGauge) With(labelValues ...string) metrics.Gauge {
+	return testGauge{tg.with(labelValues...)}
+}
+
+type testHistogram struct{ *testMetric }
+
+func (th testHistogram) With(labelValues ...string) metrics.Histogram {
+	return testHistogram{th.with(labelValues...)}
+}
+
+func TestMetrics(t *testing.T) {
+	requests, durations, retries, reloads, cacheRequests, droppedLines := newTestMetric(), newTestMetric(), newTestMetric(), newTestMetric(), newTestMetric(), newTestMetric()
+	m := &Metrics{
+		Requests:              requests,
+		RequestDurations:      testHistogram{durations},
+		OpenConnections:       testGauge{newTestMetric()},
+		Retries:               retries,
+		CircuitBreakerTrips:   newTestMetric(),
+		ConfigReloads:         reloads,
+		LastConfigReload:      testGauge{newTestMetric()},
+		ServerUp:              testGauge{newTestMetric()},
+		CacheRequests:         cacheRequests,
+		AccessLogDroppedLines: droppedLines,
+	}
+
+	// the first attempt fails, the request is retried on another server
+	attempt := 0
+	server := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		attempt++
+		if attempt == 1 {
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+	})
+	backend := m.NewBackendMetrics(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		NewSaveBackend(server, "backend1").ServeHTTP(httptest.NewRecorder(), r)
+		NewSaveBackend(server, "backend1").ServeHTTP(rw, r)
+	}), "backend1")
+	frontend := NewSaveFrontend(backend, "frontend1")
+
+	req, _ := http.NewRequest("POST", "http://test.localhost/", nil)
+	NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		m.ForEntryPoint("http").ServeHTTP(rw, r, frontend.ServeHTTP)
+	})
+	assert.Equal(t, map[string]float64{"entrypoint,http,frontend,frontend1,backend,backend1,method,POST,code,201": 1}, requests.values)
+	assert.Equal(t, requests.values, durations.values)
+	assert.Equal(t, map[string]float64{"backend,backend1": 1}, retries.values)
+
+	m.ConfigReloaded(true)
+	m.ConfigReloaded(false)
+	m.ConfigReloaded(true)
+	assert.Equal(t, map[string]float64{"status,success": 2, "status,failure": 1}, reloads.values)
+
+	cacheRequested := m.CacheRequested("frontend1")
+	cacheRequested(cacheMiss)
+	cacheRequested(cacheHit)
+	cacheRequested(cacheHit)
+	assert.Equal(t, map[string]float64{"frontend,frontend1,status,miss": 1, "frontend,frontend1,status,hit": 2}, cacheRequests.values)
+
+	m.AccessLogLineDropped()
+	assert.Equal(t, map[string]float64{"": 1}, droppedLines.values)
+}
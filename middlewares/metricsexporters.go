@@ -0,0 +1,282 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/dogstatsd"
+	"github.com/go-kit/kit/metrics/influx"
+	"github.com/go-kit/kit/metrics/multi"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/go-kit/kit/metrics/statsd"
+	"github.com/go-kit/kit/util/conn"
+	influxdb "github.com/influxdata/influxdb/client/v2"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewPrometheusMetrics returns new Metrics registered in the Prometheus default registry, with the
+// request duration histograms split into buckets, and the handler exposing them.
+func NewPrometheusMetrics(buckets []float64) (*Metrics, http.Handler) {
+	requestLabels := []string{"entrypoint", "frontend", "backend", "method", "code"}
+	return &Metrics{
+		Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "requests_total",
+			Help:      "How many HTTP requests were processed, by entry point, frontend, backend, method and status code.",
+		}, requestLabels),
+		RequestDurations: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "traefik",
+			Name:      "request_duration_seconds",
+			Help:      "How long it took to process the HTTP requests, by entry point, frontend, backend, method and status code.",
+			Buckets:   buckets,
+		}, requestLabels),
+		OpenConnections: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "traefik",
+			Name:      "open_connections",
+			Help:      "How many connections are open, by entry point.",
+		}, []string{"entrypoint"}),
+		Retries: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "backend_retries_total",
+			Help:      "How many requests were retried on another server, by backend.",
+		}, []string{"backend"}),
+		CircuitBreakerTrips: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "backend_circuit_breaker_trips_total",
+			Help:      "How many times the circuit breaker of a backend tripped, by backend.",
+		}, []string{"backend"}),
+		ConfigReloads: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "config_reloads_total",
+			Help:      "How many configuration reloads were attempted, by status.",
+		}, []string{"status"}),
+		LastConfigReload: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "traefik",
+			Name:      "config_last_reload_timestamp_seconds",
+			Help:      "When the configuration was last reloaded, by status.",
+		}, []string{"status"}),
+		ServerUp: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "traefik",
+			Name:      "backend_server_up",
+			Help:      "Whether a server of a backend is up (1) or ejected or removed (0), by backend and URL.",
+		}, []string{"backend", "url"}),
+		CacheRequests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "cache_requests_total",
+			Help:      "How many requests were served by the response cache of a frontend, by frontend and cache status.",
+		}, []string{"frontend", "status"}),
+		AccessLogDroppedLines: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "traefik",
+			Name:      "accesslog_dropped_lines_total",
+			Help:      "How many access log lines were dropped because the writes to the file were too slow.",
+		}, []string{}),
+	}, promhttp.Handler()
+}
+
+// NewStatsDMetrics returns new Metrics sent every pushInterval to the StatsD server at address, over UDP.
+// The names of the metrics start with prefix. StatsD has no tags, so the metrics are not split by labels.
+func NewStatsDMetrics(address string, prefix string, pushInterval time.Duration) *Metrics {
+	s := statsd.New(prefix, kitLogger{})
+	m := &Metrics{
+		Requests:              s.NewCounter("requests", 1),
+		RequestDurations:      millisecondsHistogram{s.NewTiming("request_duration", 1)},
+		OpenConnections:       s.NewGauge("open_connections"),
+		Retries:               s.NewCounter("backend_retries", 1),
+		CircuitBreakerTrips:   s.NewCounter("backend_circuit_breaker_trips", 1),
+		ConfigReloads:         s.NewCounter("config_reloads", 1),
+		LastConfigReload:      s.NewGauge("config_last_reload"),
+		ServerUp:              s.NewGauge("backend_server_up"),
+		CacheRequests:         s.NewCounter("cache_requests", 1),
+		AccessLogDroppedLines: s.NewCounter("accesslog_dropped_lines", 1),
+	}
+	writer := conn.NewDefaultManager("udp", address, kitLogger{})
+	m.stops = append(m.stops, push(pushInterval, func(ticks <-chan time.Time) {
+		s.WriteLoop(ticks, writer)
+	}))
+	return m
+}
+
+// NewDogStatsDMetrics returns new Metrics sent every pushInterval to the DogStatsD server at address,
+// over UDP. The names of the metrics start with prefix, and their labels are sent as tags.
+func NewDogStatsDMetrics(address string, prefix string, pushInterval time.Duration) *Metrics {
+	d := dogstatsd.New(prefix, kitLogger{})
+	m := &Metrics{
+		Requests:              d.NewCounter("requests", 1),
+		RequestDurations:      millisecondsHistogram{d.NewTiming("request_duration", 1)},
+		OpenConnections:       d.NewGauge("open_connections"),
+		Retries:               d.NewCounter("backend_retries", 1),
+		CircuitBreakerTrips:   d.NewCounter("backend_circuit_breaker_trips", 1),
+		ConfigReloads:         d.NewCounter("config_reloads", 1),
+		LastConfigReload:      d.NewGauge("config_last_reload"),
+		ServerUp:              d.NewGauge("backend_server_up"),
+		CacheRequests:         d.NewCounter("cache_requests", 1),
+		AccessLogDroppedLines: d.NewCounter("accesslog_dropped_lines", 1),
+	}
+	writer := conn.NewDefaultManager("udp", address, kitLogger{})
+	m.stops = append(m.stops, push(pushInterval, func(ticks <-chan time.Time) {
+		d.WriteLoop(ticks, writer)
+	}))
+	return m
+}
+
+// InfluxDBOptions configures the InfluxDB metrics.
+type InfluxDBOptions struct {
+	// Protocol is "udp" or "http".
+	Protocol string
+	// Address is the host:port of the UDP listener, or the URL of the HTTP API.
+	Address string
+	// Database and RetentionPolicy are the ones the HTTP API writes to.
+	Database        string
+	RetentionPolicy string
+	Username        string
+	Password        string
+	// Prefix starts the names of the measurements.
+	Prefix       string
+	PushInterval time.Duration
+}
+
+// NewInfluxDBMetrics returns new Metrics written every pushInterval to InfluxDB in the line protocol.
+// The labels of the metrics are written as tags, and the request durations are in seconds.
+func NewInfluxDBMetrics(options InfluxDBOptions) (*Metrics, error) {
+	var client influxdb.Client
+	var err error
+	switch options.Protocol {
+	case "udp":
+		client, err = influxdb.NewUDPClient(influxdb.UDPConfig{Addr: options.Address})
+	case "http":
+		client, err = influxdb.NewHTTPClient(influxdb.HTTPConfig{Addr: options.Address, Username: options.Username, Password: options.Password})
+	default:
+		return nil, errors.New("Unknown InfluxDB protocol " + options.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	in := influx.New(map[string]string{}, influxdb.BatchPointsConfig{
+		Database:        options.Database,
+		RetentionPolicy: options.RetentionPolicy,
+	}, kitLogger{})
+	m := &Metrics{
+		Requests:              in.NewCounter(options.Prefix + "requests"),
+		RequestDurations:      in.NewHistogram(options.Prefix + "request_duration"),
+		OpenConnections:       in.NewGauge(options.Prefix + "open_connections"),
+		Retries:               in.NewCounter(options.Prefix + "backend_retries"),
+		CircuitBreakerTrips:   in.NewCounter(options.Prefix + "backend_circuit_breaker_trips"),
+		ConfigReloads:         in.NewCounter(options.Prefix + "config_reloads"),
+		LastConfigReload:      in.NewGauge(options.Prefix + "config_last_reload"),
+		ServerUp:              in.NewGauge(options.Prefix + "backend_server_up"),
+		CacheRequests:         in.NewCounter(options.Prefix + "cache_requests"),
+		AccessLogDroppedLines: in.NewCounter(options.Prefix + "accesslog_dropped_lines"),
+	}
+	stop := push(options.PushInterval, func(ticks <-chan time.Time) {
+		in.WriteLoop(ticks, client)
+	})
+	m.stops = append(m.stops, func() {
+		stop()
+		client.Close()
+	})
+	return m, nil
+}
+
+// NewMultiMetrics returns new Metrics recording the measurements in all of metricsList.
+func NewMultiMetrics(metricsList ...*Metrics) *Metrics {
+	m := &Metrics{}
+	var requests, retries, circuitBreakerTrips, configReloads, cacheRequests, accessLogDroppedLines []metrics.Counter
+	var requestDurations []metrics.Histogram
+	var openConnections, lastConfigReload, serverUp []metrics.Gauge
+	for _, other := range metricsList {
+		requests = append(requests, other.Requests)
+		requestDurations = append(requestDurations, other.RequestDurations)
+		openConnections = append(openConnections, other.OpenConnections)
+		retries = append(retries, other.Retries)
+		circuitBreakerTrips = append(circuitBreakerTrips, other.CircuitBreakerTrips)
+		configReloads = append(configReloads, other.ConfigReloads)
+		lastConfigReload = append(lastConfigReload, other.LastConfigReload)
+		serverUp = append(serverUp, other.ServerUp)
+		cacheRequests = append(cacheRequests, other.CacheRequests)
+		accessLogDroppedLines = append(accessLogDroppedLines, other.AccessLogDroppedLines)
+		m.stops = append(m.stops, other.stops...)
+	}
+	m.Requests = multi.NewCounter(requests...)
+	m.RequestDurations = multi.NewHistogram(requestDurations...)
+	m.OpenConnections = multi.NewGauge(openConnections...)
+	m.Retries = multi.NewCounter(retries...)
+	m.CircuitBreakerTrips = multi.NewCounter(circuitBreakerTrips...)
+	m.ConfigReloads = multi.NewCounter(configReloads...)
+	m.LastConfigReload = multi.NewGauge(lastConfigReload...)
+	m.ServerUp = multi.NewGauge(serverUp...)
+	m.CacheRequests = multi.NewCounter(cacheRequests...)
+	m.AccessLogDroppedLines = multi.NewCounter(accessLogDroppedLines...)
+	return m
+}
+
+// Close pushes the measurements not pushed yet, and stops pushing them.
+func (m *Metrics) Close() {
+	m.mutex.Lock()
+	stops := m.stops
+	m.stops = nil
+	m.mutex.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// push runs writeLoop with a channel ticking every pushInterval, until the returned function is called.
+// The function ticks a last time, so that the measurements not pushed yet are written, and waits for writeLoop.
+func push(pushInterval time.Duration, writeLoop func(ticks <-chan time.Time)) func() {
+	ticker := time.NewTicker(pushInterval)
+	ticks := make(chan time.Time)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writeLoop(ticks)
+	}()
+	go func() {
+		defer close(ticks)
+		defer ticker.Stop()
+		for {
+			select {
+			case tick := <-ticker.C:
+				ticks <- tick
+			case <-stop:
+				ticks <- time.Now()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// millisecondsHistogram observes in milliseconds the durations in seconds, for the StatsD timings.
+type millisecondsHistogram struct {
+	histogram metrics.Histogram
+}
+
+func (mh millisecondsHistogram) With(labelValues ...string) metrics.Histogram {
+	return millisecondsHistogram{mh.histogram.With(labelValues...)}
+}
+
+func (mh millisecondsHistogram) Observe(value float64) {
+	mh.histogram.Observe(value * 1000)
+}
+
+// kitLogger logs the errors of the metrics exporters with logrus.
+type kitLogger struct{}
+
+func (kitLogger) Log(keyvals ...interface{}) error {
+	pairs := make([]string, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", keyvals[i], keyvals[i+1]))
+	}
+	log.Errorf("Error pushing metrics: %s", strings.Join(pairs, " "))
+	return nil
+}
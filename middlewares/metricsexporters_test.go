@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenUDP returns a local UDP listener and a function returning what it received until all the
+// expected strings are found or the timeout expires.
+func listenUDP(t *testing.T) (*net.UDPConn, func(expected ...string) string) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener, func(expected ...string) string {
+		received := ""
+		buffer := make([]byte, 65536)
+		listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			found := true
+			for _, s := range expected {
+				found = found && strings.Contains(received, s)
+			}
+			if found {
+				return received
+			}
+			n, err := listener.Read(buffer)
+			if err != nil {
+				return received
+			}
+			received += string(buffer[:n])
+		}
+	}
+}
+
+// serveMetrics records a request served by frontend1 and backend1, a configuration reload and a dropped
+// access log line.
+func serveMetrics(m *Metrics) {
+	backend := m.NewBackendMetrics(NewSaveBackend(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}), "backend1"), "backend1")
+	frontend := NewSaveFrontend(backend, "frontend1")
+	req, _ := http.NewRequest("GET", "http://test.localhost/", nil)
+	NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		m.ForEntryPoint("http").ServeHTTP(rw, r, frontend.ServeHTTP)
+	})
+	m.ConfigReloaded(true)
+	m.AccessLogLineDropped()
+}
+
+func TestDogStatsDMetrics(t *testing.T) {
+	listener, receive := listenUDP(t)
+	defer listener.Close()
+
+	m := NewDogStatsDMetrics(listener.LocalAddr().String(), "traefik.", 10*time.Millisecond)
+	defer m.Close()
+	serveMetrics(m)
+	received := receive(
+		"traefik.requests:1.000000|c|#entrypoint:http,frontend:frontend1,backend:backend1,method:GET,code:200\n",
+		"traefik.request_duration:",
+		"traefik.config_reloads:1.000000|c|#status:success\n",
+		"traefik.accesslog_dropped_lines:1.000000|c\n")
+	assert.Contains(t, received, "traefik.requests:1.000000|c|#entrypoint:http,frontend:frontend1,backend:backend1,method:GET,code:200\n")
+	assert.Contains(t, received, "traefik.request_duration:")
+	assert.Contains(t, received, "traefik.config_reloads:1.000000|c|#status:success\n")
+	assert.Contains(t, received, "traefik.accesslog_dropped_lines:1.000000|c\n")
+}
+
+func TestInfluxDBMetrics(t *testing.T) {
+	listener, receive := listenUDP(t)
+	defer listener.Close()
+
+	m, err := NewInfluxDBMetrics(InfluxDBOptions{Protocol: "udp", Address: listener.LocalAddr().String(), Prefix: "traefik.", PushInterval: time.Hour})
+	assert.NoError(t, err)
+	serveMetrics(m)
+	// the measurements not pushed yet are pushed when the metrics are closed
+	m.Close()
+	received := receive("traefik.requests,", "traefik.config_reloads,")
+	assert.Contains(t, received, "traefik.requests,backend=backend1,code=200,entrypoint=http,frontend=frontend1,method=GET count=1 ")
+	assert.Contains(t, received, "traefik.request_duration,backend=backend1,code=200,entrypoint=http,frontend=frontend1,method=GET ")
+	assert.Contains(t, received, "traefik.config_reloads,status=success count=1 ")
+
+	_, err = NewInfluxDBMetrics(InfluxDBOptions{Protocol: "tcp"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+)
+
+// Mirror forwards requests to a handler and sends a copy of a sample of them
+// to a mirror handler, discarding the mirror's response.
+type Mirror struct {
+	handler     http.Handler
+	mirror      http.Handler
+	percent     uint64
+	maxBodySize int64
+	inFlight    chan struct{}
+	total       uint64
+	requests    uint64
+	errors      uint64
+	dropped     uint64
+}
+
+// MirrorStats holds the counters of a Mirror.
+type MirrorStats struct {
+	Requests uint64 `json:"requests"`
+	Errors   uint64 `json:"errors"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// NewMirror creates a Mirror sending percent % of the requests handled by handler to mirror.
+// Requests with a body bigger than maxBodySize are never mirrored, and the requests sampled while
+// maxInFlight mirrored requests are still in progress are dropped.
+func NewMirror(handler http.Handler, mirror http.Handler, percent int, maxBodySize int64, maxInFlight int) *Mirror {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	return &Mirror{
+		handler:     handler,
+		mirror:      mirror,
+		percent:     uint64(percent),
+		maxBodySize: maxBodySize,
+		inFlight:    make(chan struct{}, maxInFlight),
+	}
+}
+
+func (m *Mirror) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if m.sample() && len(r.Header.Get("Upgrade")) == 0 {
+		m.mirrorRequest(r)
+	}
+	m.handler.ServeHTTP(rw, r)
+}
+
+// mirrorRequest sends a copy of r to the mirror in the background, unless too many mirrored
+// requests are already in progress: a slow mirror must not pile up goroutines and bodies.
+func (m *Mirror) mirrorRequest(r *http.Request) {
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		atomic.AddUint64(&m.dropped, 1)
+		return
+	}
+	mirrorRequest, ok := m.copyRequest(r)
+	if !ok {
+		<-m.inFlight
+		return
+	}
+	atomic.AddUint64(&m.requests, 1)
+	safe.Go(func() {
+		defer func() { <-m.inFlight }()
+		mirrorRw := &discardResponseWriter{header: make(http.Header)}
+		m.mirror.ServeHTTP(mirrorRw, mirrorRequest)
+		if mirrorRw.status == 0 || mirrorRw.status >= http.StatusInternalServerError {
+			atomic.AddUint64(&m.errors, 1)
+		}
+	})
+}
+
+// Stats returns the current counters of the Mirror.
+func (m *Mirror) Stats() MirrorStats {
+	return MirrorStats{
+		Requests: atomic.LoadUint64(&m.requests),
+		Errors:   atomic.LoadUint64(&m.errors),
+		Dropped:  atomic.LoadUint64(&m.dropped),
+	}
+}
+
+// sample spreads mirrored requests evenly: the nth request is mirrored
+// if it makes n*percent/100 reach a new integer.
+func (m *Mirror) sample() bool {
+	n := atomic.AddUint64(&m.total, 1)
+	return n*m.percent/100 != (n-1)*m.percent/100
+}
+
+// copyRequest buffers the request body (up to maxBodySize) and returns a copy
+// of the request that can be sent concurrently with the original one.
+func (m *Mirror) copyRequest(r *http.Request) (*http.Request, bool) {
+	var body []byte
+	if r.Body != nil {
+		if r.ContentLength > m.maxBodySize {
+			return nil, false
+		}
+		buffer, err := ioutil.ReadAll(io.LimitReader(r.Body, m.maxBodySize+1))
+		r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(buffer), r.Body))
+		if err != nil {
+			log.Debugf("Error reading body for mirroring %s", err)
+			return nil, false
+		}
+		if int64(len(buffer)) > m.maxBodySize {
+			return nil, false
+		}
+		body = buffer
+	}
+
+	mirrorRequest := new(http.Request)
+	*mirrorRequest = *r
+	mirrorURL := *r.URL
+	mirrorRequest.URL = &mirrorURL
+	mirrorRequest.Header = make(http.Header)
+	for key, values := range r.Header {
+		mirrorRequest.Header[key] = append([]string(nil), values...)
+	}
+	// the mirrored request gets its own ID, not to be attributed to the original one in the access log
+	if requestIDHeader, _ := getRequestID(r); len(requestIDHeader) > 0 {
+		mirrorRequest.Header.Set(requestIDHeader, newUUID())
+	}
+	removeRequestInfo(mirrorRequest)
+	mirrorRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	mirrorRequest.ContentLength = int64(len(body))
+	return mirrorRequest, true
+}
+
+// discardResponseWriter is a http.ResponseWriter dropping the body it receives
+// and keeping only the status code.
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (drw *discardResponseWriter) Header() http.Header {
+	return drw.header
+}
+
+func (drw *discardResponseWriter) Write(b []byte) (int, error) {
+	if drw.status == 0 {
+		drw.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (drw *discardResponseWriter) WriteHeader(status int) {
+	drw.status = status
+}
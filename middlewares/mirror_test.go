@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorSampling(t *testing.T) {
+	percents := []struct {
+		percent  int
+		expected uint64
+	}{
+		{percent: 0, expected: 0},
+		{percent: 10, expected: 10},
+		{percent: 25, expected: 25},
+		{percent: 100, expected: 100},
+	}
+
+	for _, p := range percents {
+		var wg sync.WaitGroup
+		handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+		mirrorHandler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+			wg.Done()
+		})
+		mirror := NewMirror(handler, mirrorHandler, p.percent, 1024, 100)
+		wg.Add(int(p.expected))
+		for i := 0; i < 100; i++ {
+			req, _ := http.NewRequest("GET", "http://localhost/", nil)
+			mirror.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		wg.Wait()
+		assert.Equal(t, MirrorStats{Requests: p.expected}, mirror.Stats(), "percent %d", p.percent)
+	}
+}
+
+func TestMirrorBody(t *testing.T) {
+	var wg sync.WaitGroup
+	var mirroredBody []byte
+	var mirroredRequestID string
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		rw.Write(body)
+	})
+	mirrorHandler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		defer wg.Done()
+		mirroredBody, _ = ioutil.ReadAll(r.Body)
+		mirroredRequestID = r.Header.Get("X-Request-ID")
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+	mirror := NewMirror(handler, mirrorHandler, 100, 5, 100)
+
+	wg.Add(1)
+	req, _ := http.NewRequest("POST", "http://localhost/", bytes.NewBufferString("hello"))
+	req.Header.Set("X-Request-ID", "request1")
+	recorder := httptest.NewRecorder()
+	serveWithRequestID(recorder, req, mirror.ServeHTTP)
+	wg.Wait()
+	assert.Equal(t, "hello", recorder.Body.String())
+	assert.Equal(t, "hello", string(mirroredBody))
+	assert.NotEmpty(t, mirroredRequestID)
+	assert.NotEqual(t, "request1", mirroredRequestID, "the mirrored request gets its own ID")
+	// the error is counted once the mirror handler has returned
+	for i := 0; i < 100 && mirror.Stats().Errors == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, MirrorStats{Requests: 1, Errors: 1}, mirror.Stats())
+
+	// bodies bigger than the buffer are forwarded but not mirrored
+	req, _ = http.NewRequest("POST", "http://localhost/", bytes.NewBufferString("hello world"))
+	recorder = httptest.NewRecorder()
+	mirror.ServeHTTP(recorder, req)
+	assert.Equal(t, "hello world", recorder.Body.String())
+	assert.Equal(t, MirrorStats{Requests: 1, Errors: 1}, mirror.Stats())
+
+	req, _ = http.NewRequest("POST", "http://localhost/", ioutil.NopCloser(bytes.NewBufferString("hello world")))
+	recorder = httptest.NewRecorder()
+	mirror.ServeHTTP(recorder, req)
+	assert.Equal(t, "hello world", recorder.Body.String())
+	assert.Equal(t, MirrorStats{Requests: 1, Errors: 1}, mirror.Stats())
+}
+
+func TestMirrorMaxInFlight(t *testing.T) {
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	mirrorHandler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		defer wg.Done()
+		<-release
+		rw.WriteHeader(http.StatusOK)
+	})
+	mirror := NewMirror(handler, mirrorHandler, 100, 1024, 2)
+
+	// the mirror is stuck: the requests beyond the 2 in flight are not mirrored
+	wg.Add(2)
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		recorder := httptest.NewRecorder()
+		mirror.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	assert.Equal(t, MirrorStats{Requests: 2, Dropped: 3}, mirror.Stats())
+	close(release)
+	wg.Wait()
+
+	// once the mirror caught up, requests are mirrored again
+	for i := 0; i < 100 && len(mirror.inFlight) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Add(1)
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	mirror.ServeHTTP(httptest.NewRecorder(), req)
+	wg.Wait()
+	assert.Equal(t, MirrorStats{Requests: 3, Dropped: 3}, mirror.Stats())
+}
@@ -0,0 +1,295 @@
+package middlewares
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/oxy/roundrobin"
+)
+
+// ServerBalancer is a load-balancer whose servers can be removed and added back,
+// like oxy roundrobin.RoundRobin and roundrobin.Rebalancer.
+type ServerBalancer interface {
+	RemoveServer(u *url.URL) error
+	UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error
+}
+
+// OutlierDetector sits between a load-balancer and the forwarder and ejects from the
+// load-balancer the servers returning consecutive 5xx responses or network errors.
+// The ejection time of a server doubles each time it is ejected again.
+type OutlierDetector struct {
+	backend             string
+	next                http.Handler
+	balancer            ServerBalancer
+	consecutiveFailures int
+	baseEjectionTime    time.Duration
+	maxEjectionTime     time.Duration
+	maxEjectionPercent  int
+	mutex               sync.Mutex
+	servers             map[string]*outlierServer
+	stateChanged        func(serverURL string, up bool)
+	// replaced by another OutlierDetector which inherited its ejections
+	retired bool
+}
+
+type outlierServer struct {
+	url          *url.URL
+	weight       int
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+	restoredAt   time.Time
+	timer        *time.Timer
+}
+
+// EjectedServer holds the ejection state of a server.
+type EjectedServer struct {
+	URL       string    `json:"url"`
+	Ejections int       `json:"ejections"`
+	Until     time.Time `json:"until"`
+}
+
+// NewOutlierDetector returns a new OutlierDetector for the servers of backend, forwarding requests to next.
+func NewOutlierDetector(backend string, next http.Handler, consecutiveFailures int, baseEjectionTime time.Duration, maxEjectionTime time.Duration, maxEjectionPercent int) *OutlierDetector {
+	return &OutlierDetector{
+		backend:             backend,
+		next:                next,
+		consecutiveFailures: consecutiveFailures,
+		baseEjectionTime:    baseEjectionTime,
+		maxEjectionTime:     maxEjectionTime,
+		maxEjectionPercent:  maxEjectionPercent,
+		servers:             map[string]*outlierServer{},
+	}
+}
+
+// OnStateChange sets the function called when a server is ejected or restored.
+func (od *OutlierDetector) OnStateChange(stateChanged func(serverURL string, up bool)) {
+	od.stateChanged = stateChanged
+}
+
+// SetBalancer sets the load-balancer servers are ejected from.
+func (od *OutlierDetector) SetBalancer(balancer ServerBalancer) {
+	od.balancer = balancer
+}
+
+// AddServer registers a server of the load-balancer, with the weight to use when adding it back.
+func (od *OutlierDetector) AddServer(u *url.URL, weight int) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	od.servers[u.String()] = &outlierServer{url: u, weight: weight}
+}
+
+// UpsertServer registers a new server or changes the weight of a server, and adds it
+// to the load-balancer unless it is currently ejected.
+func (od *OutlierDetector) UpsertServer(u *url.URL, weight int) error {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	server, ok := od.servers[u.String()]
+	if !ok {
+		server = &outlierServer{url: u}
+		od.servers[u.String()] = server
+	}
+	server.weight = weight
+	if !server.ejectedUntil.IsZero() {
+		return nil
+	}
+	return od.balancer.UpsertServer(u, roundrobin.Weight(weight))
+}
+
+// RemoveServer unregisters a server and removes it from the load-balancer.
+func (od *OutlierDetector) RemoveServer(u *url.URL) error {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	if server, ok := od.servers[u.String()]; ok {
+		if server.timer != nil {
+			server.timer.Stop()
+		}
+		delete(od.servers, u.String())
+		if !server.ejectedUntil.IsZero() {
+			return nil
+		}
+	}
+	return od.balancer.RemoveServer(u)
+}
+
+func (od *OutlierDetector) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	recorder := &statusResponseWriter{ResponseWriter: rw}
+	od.next.ServeHTTP(recorder, r)
+	od.record(r.URL.String(), recorder.status >= http.StatusInternalServerError)
+}
+
+func (od *OutlierDetector) record(serverURL string, failed bool) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	server, ok := od.servers[serverURL]
+	if !ok {
+		return
+	}
+	if !failed {
+		server.failures = 0
+		return
+	}
+	server.failures++
+	if server.failures >= od.consecutiveFailures && server.ejectedUntil.IsZero() {
+		if !od.canEject() {
+			log.Warnf("Server %s of backend %s failed %d times but the maximum number of ejected servers is reached", serverURL, od.backend, server.failures)
+			return
+		}
+		now := time.Now()
+		if !server.restoredAt.IsZero() && now.Sub(server.restoredAt) > od.maxEjectionTime {
+			server.ejections = 0
+		}
+		server.ejections++
+		duration := od.baseEjectionTime
+		for i := 1; i < server.ejections && duration < od.maxEjectionTime; i++ {
+			duration *= 2
+		}
+		if duration > od.maxEjectionTime {
+			duration = od.maxEjectionTime
+		}
+		log.Warnf("Ejecting server %s from backend %s for %s after %d consecutive failures", serverURL, od.backend, duration, server.failures)
+		od.eject(server, now.Add(duration))
+	}
+}
+
+// canEject checks that one more server can be ejected without exceeding maxEjectionPercent.
+// At least one server can always be ejected, but never the last one.
+func (od *OutlierDetector) canEject() bool {
+	ejected := 0
+	for _, server := range od.servers {
+		if !server.ejectedUntil.IsZero() {
+			ejected++
+		}
+	}
+	maxEjected := len(od.servers) * od.maxEjectionPercent / 100
+	if maxEjected < 1 {
+		maxEjected = 1
+	}
+	return ejected < maxEjected && ejected+1 < len(od.servers)
+}
+
+func (od *OutlierDetector) eject(server *outlierServer, until time.Time) {
+	if err := od.balancer.RemoveServer(server.url); err != nil {
+		log.Errorf("Error ejecting server %s from backend %s: %s", server.url, od.backend, err)
+		return
+	}
+	od.setEjected(server, until)
+	if od.stateChanged != nil {
+		od.stateChanged(server.url.String(), false)
+	}
+}
+
+// setEjected marks a server removed from the load-balancer as ejected, and restores it at until.
+func (od *OutlierDetector) setEjected(server *outlierServer, until time.Time) {
+	server.failures = 0
+	server.ejectedUntil = until
+	server.timer = time.AfterFunc(until.Sub(time.Now()), func() {
+		od.restore(server)
+	})
+}
+
+func (od *OutlierDetector) restore(server *outlierServer) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	if od.retired || od.servers[server.url.String()] != server {
+		// removed from the configuration, or inherited by another OutlierDetector meanwhile
+		return
+	}
+	log.Infof("Restoring server %s in backend %s", server.url, od.backend)
+	if err := od.balancer.UpsertServer(server.url, roundrobin.Weight(server.weight)); err != nil {
+		log.Errorf("Error restoring server %s in backend %s: %s", server.url, od.backend, err)
+	}
+	server.ejectedUntil = time.Time{}
+	server.restoredAt = time.Now()
+	if od.stateChanged != nil {
+		od.stateChanged(server.url.String(), true)
+	}
+}
+
+// Ejected returns the servers currently ejected.
+func (od *OutlierDetector) Ejected() []EjectedServer {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	ejected := []EjectedServer{}
+	for serverURL, server := range od.servers {
+		if !server.ejectedUntil.IsZero() {
+			ejected = append(ejected, EjectedServer{URL: serverURL, Ejections: server.ejections, Until: server.ejectedUntil})
+		}
+	}
+	sort.Sort(ejectedServers(ejected))
+	return ejected
+}
+
+// Inherit keeps the ejections of the servers of a previous OutlierDetector of the same backend,
+// so that a configuration reload does not add back servers still ejected. It must be called once
+// od replaces previous: previous no longer restores its servers. The servers still ejected were
+// already reported down, so the state change function is not called again.
+func (od *OutlierDetector) Inherit(previous *OutlierDetector) {
+	previous.mutex.Lock()
+	defer previous.mutex.Unlock()
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	previous.retired = true
+	for serverURL, previousServer := range previous.servers {
+		if previousServer.timer != nil {
+			previousServer.timer.Stop()
+		}
+		server, ok := od.servers[serverURL]
+		if !ok {
+			continue
+		}
+		server.ejections = previousServer.ejections
+		server.restoredAt = previousServer.restoredAt
+		if !previousServer.ejectedUntil.IsZero() {
+			if err := od.balancer.RemoveServer(server.url); err != nil {
+				log.Errorf("Error ejecting server %s from backend %s: %s", server.url, od.backend, err)
+				continue
+			}
+			od.setEjected(server, previousServer.ejectedUntil)
+		}
+	}
+}
+
+type ejectedServers []EjectedServer
+
+func (e ejectedServers) Len() int           { return len(e) }
+func (e ejectedServers) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e ejectedServers) Less(i, j int) bool { return e[i].URL < e[j].URL }
+
+// statusResponseWriter is a http.ResponseWriter keeping the status code of the response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (srw *statusResponseWriter) Write(b []byte) (int, error) {
+	if srw.status == 0 {
+		srw.status = http.StatusOK
+	}
+	return srw.ResponseWriter.Write(b)
+}
+
+func (srw *statusResponseWriter) WriteHeader(status int) {
+	srw.status = status
+	srw.ResponseWriter.WriteHeader(status)
+}
+
+func (srw *statusResponseWriter) Flush() {
+	if flusher, ok := srw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (srw *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return srw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (srw *statusResponseWriter) CloseNotify() <-chan bool {
+	return srw.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
@@ -0,0 +1,170 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/oxy/roundrobin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBalancer struct {
+	mutex   sync.Mutex
+	servers map[string]bool
+}
+
+func (fb *fakeBalancer) RemoveServer(u *url.URL) error {
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	delete(fb.servers, u.String())
+	return nil
+}
+
+func (fb *fakeBalancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	fb.servers[u.String()] = true
+	return nil
+}
+
+func (fb *fakeBalancer) has(serverURL string) bool {
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	return fb.servers[serverURL]
+}
+
+func newTestOutlierDetector(baseEjectionTime time.Duration, maxEjectionPercent int, serverURLs ...string) (*OutlierDetector, *fakeBalancer) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Fail") != "" {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+	balancer := &fakeBalancer{servers: map[string]bool{}}
+	outlierDetector := NewOutlierDetector("backend1", handler, 2, baseEjectionTime, 4*baseEjectionTime, maxEjectionPercent)
+	outlierDetector.SetBalancer(balancer)
+	for _, serverURL := range serverURLs {
+		u, _ := url.Parse(serverURL)
+		outlierDetector.AddServer(u, 1)
+		balancer.UpsertServer(u)
+	}
+	return outlierDetector, balancer
+}
+
+func sendToServer(handler http.Handler, serverURL string, fail bool) {
+	req, _ := http.NewRequest("GET", serverURL, nil)
+	if fail {
+		req.Header.Set("X-Fail", "true")
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestOutlierDetectorEjection(t *testing.T) {
+	outlierDetector, balancer := newTestOutlierDetector(50*time.Millisecond, 50, "http://10.0.0.1", "http://10.0.0.2")
+
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	sendToServer(outlierDetector, "http://10.0.0.1", false)
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	assert.True(t, balancer.has("http://10.0.0.1"), "failures are not consecutive")
+
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	assert.False(t, balancer.has("http://10.0.0.1"))
+	ejected := outlierDetector.Ejected()
+	assert.Len(t, ejected, 1)
+	assert.Equal(t, "http://10.0.0.1", ejected[0].URL)
+	assert.Equal(t, 1, ejected[0].Ejections)
+
+	// the last server is never ejected
+	sendToServer(outlierDetector, "http://10.0.0.2", true)
+	sendToServer(outlierDetector, "http://10.0.0.2", true)
+	assert.True(t, balancer.has("http://10.0.0.2"))
+
+	for i := 0; i < 100 && !balancer.has("http://10.0.0.1"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, balancer.has("http://10.0.0.1"))
+	assert.Empty(t, outlierDetector.Ejected())
+
+	// ejected again for twice as long
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	ejected = outlierDetector.Ejected()
+	assert.Len(t, ejected, 1)
+	assert.Equal(t, 2, ejected[0].Ejections)
+	assert.True(t, ejected[0].Until.Sub(time.Now()) > 50*time.Millisecond)
+}
+
+func TestOutlierDetectorMaxEjectionPercent(t *testing.T) {
+	outlierDetector, balancer := newTestOutlierDetector(time.Minute, 50, "http://10.0.0.1", "http://10.0.0.2", "http://10.0.0.3", "http://10.0.0.4")
+
+	for _, serverURL := range []string{"http://10.0.0.1", "http://10.0.0.2", "http://10.0.0.3"} {
+		sendToServer(outlierDetector, serverURL, true)
+		sendToServer(outlierDetector, serverURL, true)
+	}
+	assert.False(t, balancer.has("http://10.0.0.1"))
+	assert.False(t, balancer.has("http://10.0.0.2"))
+	assert.True(t, balancer.has("http://10.0.0.3"))
+	assert.Len(t, outlierDetector.Ejected(), 2)
+}
+
+func TestOutlierDetectorInherit(t *testing.T) {
+	previous, _ := newTestOutlierDetector(time.Minute, 50, "http://10.0.0.1", "http://10.0.0.2")
+	sendToServer(previous, "http://10.0.0.1", true)
+	sendToServer(previous, "http://10.0.0.1", true)
+
+	outlierDetector, balancer := newTestOutlierDetector(time.Minute, 50, "http://10.0.0.1", "http://10.0.0.2")
+	stateChanges := 0
+	outlierDetector.OnStateChange(func(serverURL string, up bool) {
+		stateChanges++
+	})
+	outlierDetector.Inherit(previous)
+	assert.False(t, balancer.has("http://10.0.0.1"))
+	assert.Equal(t, previous.Ejected(), outlierDetector.Ejected())
+	assert.Equal(t, 0, stateChanges, "the inherited ejections are not reported again")
+}
+
+func TestOutlierDetectorInheritRestore(t *testing.T) {
+	previous, previousBalancer := newTestOutlierDetector(50*time.Millisecond, 50, "http://10.0.0.1", "http://10.0.0.2")
+	previousStateChanges := 0
+	previous.OnStateChange(func(serverURL string, up bool) {
+		previousStateChanges++
+	})
+	sendToServer(previous, "http://10.0.0.1", true)
+	sendToServer(previous, "http://10.0.0.1", true)
+
+	outlierDetector, balancer := newTestOutlierDetector(time.Minute, 50, "http://10.0.0.1", "http://10.0.0.2")
+	outlierDetector.Inherit(previous)
+
+	// the new OutlierDetector restores the server, the previous one no longer does
+	for i := 0; i < 100 && !balancer.has("http://10.0.0.1"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, balancer.has("http://10.0.0.1"))
+	assert.Empty(t, outlierDetector.Ejected())
+	assert.False(t, previousBalancer.has("http://10.0.0.1"))
+	assert.Equal(t, 1, previousStateChanges)
+}
+
+func TestOutlierDetectorUpsertRemoveServer(t *testing.T) {
+	outlierDetector, balancer := newTestOutlierDetector(time.Minute, 50, "http://10.0.0.1", "http://10.0.0.2")
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+	sendToServer(outlierDetector, "http://10.0.0.1", true)
+
+	u1, _ := url.Parse("http://10.0.0.1")
+	outlierDetector.UpsertServer(u1, 2)
+	assert.False(t, balancer.has("http://10.0.0.1"), "ejected servers stay out of the load-balancer")
+
+	u3, _ := url.Parse("http://10.0.0.3")
+	outlierDetector.UpsertServer(u3, 1)
+	assert.True(t, balancer.has("http://10.0.0.3"))
+
+	outlierDetector.RemoveServer(u1)
+	assert.Empty(t, outlierDetector.Ejected())
+	outlierDetector.RemoveServer(u3)
+	assert.False(t, balancer.has("http://10.0.0.3"))
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/oxy/utils"
+)
+
+// Rate allows on average Average requests per Period, with bursts up to Burst requests.
+type Rate struct {
+	Period  time.Duration
+	Average int64
+	Burst   int64
+}
+
+// RateLimiter limits the rate of requests of each source, as returned by an oxy extractor.
+// A request is accepted only if it fits in all the rates.
+type RateLimiter struct {
+	handler       http.Handler
+	extractorFunc string
+	extractor     utils.SourceExtractor
+	rates         []Rate
+	store         *bucketStore
+	now           func() time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter for the given rates, using extractorFunc
+// (for example client.ip or request.header.X-Api-Key) to identify sources.
+func NewRateLimiter(handler http.Handler, extractorFunc string, rates []Rate) (*RateLimiter, error) {
+	if len(rates) == 0 {
+		return nil, errors.New("No rate defined")
+	}
+	extractor, err := utils.NewExtractor(extractorFunc)
+	if err != nil {
+		return nil, err
+	}
+	for i, rate := range rates {
+		if rate.Period <= 0 || rate.Average <= 0 {
+			return nil, errors.New("Invalid rate, period and average must be positive")
+		}
+		if rate.Burst < rate.Average {
+			rates[i].Burst = rate.Average
+		}
+	}
+	return &RateLimiter{
+		handler:       handler,
+		extractorFunc: extractorFunc,
+		extractor:     extractor,
+		rates:         rates,
+		store:         &bucketStore{buckets: map[string][]*tokenBucket{}},
+		now:           time.Now,
+	}, nil
+}
+
+func (rl *RateLimiter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	source, amount, err := rl.extractor.Extract(r)
+	if err != nil {
+		log.Errorf("Error extracting rate limiting source %s", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if delay := rl.take(source, amount); delay > 0 {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	rl.handler.ServeHTTP(rw, r)
+}
+
+// Inherit keeps the state of the sources of a previous RateLimiter, so that a
+// configuration reload does not reset the limits, as long as they did not change.
+func (rl *RateLimiter) Inherit(previous *RateLimiter) {
+	if previous.extractorFunc == rl.extractorFunc && reflect.DeepEqual(previous.rates, rl.rates) {
+		rl.store = previous.store
+	}
+}
+
+// take consumes amount tokens in each bucket of source and returns zero,
+// or returns how long to wait before retrying if a bucket is short of tokens.
+func (rl *RateLimiter) take(source string, amount int64) time.Duration {
+	now := rl.now()
+	rl.store.mutex.Lock()
+	defer rl.store.mutex.Unlock()
+	rl.store.sweep(now, rl.maxPeriod())
+
+	buckets, ok := rl.store.buckets[source]
+	if !ok {
+		for _, rate := range rl.rates {
+			buckets = append(buckets, &tokenBucket{rate: rate, tokens: float64(rate.Burst), last: now})
+		}
+		rl.store.buckets[source] = buckets
+	}
+
+	var delay time.Duration
+	for _, bucket := range buckets {
+		bucket.refill(now)
+		if wait := bucket.wait(amount); wait > delay {
+			delay = wait
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+	for _, bucket := range buckets {
+		bucket.tokens -= float64(amount)
+	}
+	return 0
+}
+
+func (rl *RateLimiter) maxPeriod() time.Duration {
+	var maxPeriod time.Duration
+	for _, rate := range rl.rates {
+		if refill := rate.Period * time.Duration(rate.Burst) / time.Duration(rate.Average); refill > maxPeriod {
+			maxPeriod = refill
+		}
+	}
+	return maxPeriod
+}
+
+// bucketStore holds the token buckets of all the sources seen by a RateLimiter.
+type bucketStore struct {
+	mutex     sync.Mutex
+	buckets   map[string][]*tokenBucket
+	lastSweep time.Time
+}
+
+// sweep forgets sources idle for longer than refillPeriod, their buckets being full again.
+func (bs *bucketStore) sweep(now time.Time, refillPeriod time.Duration) {
+	if now.Sub(bs.lastSweep) < refillPeriod {
+		return
+	}
+	for source, buckets := range bs.buckets {
+		idle := true
+		for _, bucket := range buckets {
+			if now.Sub(bucket.last) < refillPeriod {
+				idle = false
+			}
+		}
+		if idle {
+			delete(bs.buckets, source)
+		}
+	}
+	bs.lastSweep = now
+}
+
+type tokenBucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(tb.last)
+	if elapsed <= 0 {
+		return
+	}
+	tb.tokens += float64(tb.rate.Average) * float64(elapsed) / float64(tb.rate.Period)
+	if tb.tokens > float64(tb.rate.Burst) {
+		tb.tokens = float64(tb.rate.Burst)
+	}
+	tb.last = now
+}
+
+func (tb *tokenBucket) wait(amount int64) time.Duration {
+	missing := float64(amount) - tb.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing * float64(tb.rate.Period) / float64(tb.rate.Average))
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestRateLimiter(t *testing.T, extractorFunc string, rates ...Rate) (*RateLimiter, *fakeClock) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	rateLimiter, err := NewRateLimiter(handler, extractorFunc, rates)
+	assert.Nil(t, err)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	rateLimiter.now = clock.Now
+	return rateLimiter, clock
+}
+
+func rateLimitedRequest(handler http.Handler, remoteAddr string, apiKey string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Api-Key", apiKey)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	rateLimiter, clock := newTestRateLimiter(t, "client.ip", Rate{Period: time.Second, Average: 2, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "").Code)
+	}
+	recorder := rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	// other sources are not limited
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.2:1234", "").Code)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "").Code)
+}
+
+func TestRateLimiterTiers(t *testing.T) {
+	rateLimiter, clock := newTestRateLimiter(t, "request.header.X-Api-Key",
+		Rate{Period: time.Second, Average: 10},
+		Rate{Period: time.Minute, Average: 15})
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "foo").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(rateLimiter, "10.0.0.2:1234", "foo").Code)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "bar").Code)
+
+	clock.now = clock.now.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "foo").Code)
+	}
+	recorder := rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "foo")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "3", recorder.Header().Get("Retry-After"))
+}
+
+func TestRateLimiterInherit(t *testing.T) {
+	previous, _ := newTestRateLimiter(t, "client.ip", Rate{Period: time.Second, Average: 1})
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(previous, "10.0.0.1:1234", "").Code)
+
+	rateLimiter, _ := newTestRateLimiter(t, "client.ip", Rate{Period: time.Second, Average: 1})
+	rateLimiter.Inherit(previous)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "").Code)
+
+	rateLimiter, _ = newTestRateLimiter(t, "client.ip", Rate{Period: time.Second, Average: 2})
+	rateLimiter.Inherit(previous)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(rateLimiter, "10.0.0.1:1234", "").Code)
+}
+
+func TestNewRateLimiterErrors(t *testing.T) {
+	handler := http.NotFoundHandler()
+	_, err := NewRateLimiter(handler, "client.ip", []Rate{})
+	assert.NotNil(t, err)
+	_, err = NewRateLimiter(handler, "client.ip", []Rate{{Period: 0, Average: 1}})
+	assert.NotNil(t, err)
+	_, err = NewRateLimiter(handler, "foo", []Rate{{Period: time.Second, Average: 1}})
+	assert.NotNil(t, err)
+}
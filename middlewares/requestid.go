@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultRequestIDHeader is the header holding the ID of the requests, unless another one is set.
+const defaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of the request IDs accepted from the clients.
+const maxRequestIDLength = 200
+
+// RequestID gives each request an ID, forwarded to the backends and returned in the response
+// in the request ID header. The ID sent by the client in this header is kept, unless ignoreIncoming is set.
+// The ID and its header are saved in the request info, for the access log and the other middlewares.
+type RequestID struct {
+	header         string
+	generate       func() string
+	ignoreIncoming bool
+}
+
+// NewRequestID returns a new RequestID generating IDs in format ("uuid" or "ulid") in header,
+// X-Request-ID by default.
+func NewRequestID(header string, format string, ignoreIncoming bool) (*RequestID, error) {
+	var generate func() string
+	switch format {
+	case "", "uuid":
+		generate = newUUID
+	case "ulid":
+		generate = newULID
+	default:
+		return nil, fmt.Errorf("Invalid request ID format %s", format)
+	}
+	if len(header) == 0 {
+		header = defaultRequestIDHeader
+	}
+	return &RequestID{header: http.CanonicalHeaderKey(header), generate: generate, ignoreIncoming: ignoreIncoming}, nil
+}
+
+func (ri *RequestID) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID := r.Header.Get(ri.header)
+	if ri.ignoreIncoming || !validRequestID(requestID) {
+		requestID = ri.generate()
+	}
+	r.Header.Set(ri.header, requestID)
+	rw.Header().Set(ri.header, requestID)
+	saveRequestID(r, ri.header, requestID)
+	next(rw, r)
+}
+
+// validRequestID checks that a request ID sent by a client can be kept: it must be
+// a reasonably short string of printable ASCII characters.
+func validRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID: a 48 bits timestamp in milliseconds followed by 80 random bits,
+// encoded in 26 characters of Crockford's base32, so that the IDs sort by creation time.
+func newULID() string {
+	var ulid [16]byte
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(ulid[:6], timestamp[2:])
+	rand.Read(ulid[6:])
+
+	// 128 bits are encoded from the most significant bits, the first character holding only 3 bits
+	high := binary.BigEndian.Uint64(ulid[:8])
+	low := binary.BigEndian.Uint64(ulid[8:])
+	encoded := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		encoded[i] = crockfordBase32[low&0x1f]
+		low = low>>5 | high<<59
+		high >>= 5
+	}
+	return string(encoded)
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	requestID, err := NewRequestID("X-Request-ID", "uuid", false)
+	assert.NoError(t, err)
+	forwarded := ""
+	next := func(rw http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get("X-Request-ID")
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	recorder := httptest.NewRecorder()
+	requestID.ServeHTTP(recorder, req, next)
+	assert.Regexp(t, regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"), forwarded)
+	assert.Equal(t, forwarded, recorder.Header().Get("X-Request-ID"))
+
+	for incoming, kept := range map[string]bool{"client-id-1": true, "with space": false, strings.Repeat("a", 201): false} {
+		req.Header.Set("X-Request-ID", incoming)
+		requestID.ServeHTTP(httptest.NewRecorder(), req, next)
+		assert.Equal(t, kept, forwarded == incoming, incoming)
+	}
+
+	requestID, err = NewRequestID("X-Request-ID", "ulid", true)
+	assert.NoError(t, err)
+	req.Header.Set("X-Request-ID", "client-id-1")
+	requestID.ServeHTTP(httptest.NewRecorder(), req, next)
+	assert.Regexp(t, regexp.MustCompile("^[0-7][0-9A-HJKMNP-TV-Z]{25}$"), forwarded)
+
+	_, err = NewRequestID("X-Request-ID", "counter", false)
+	assert.Error(t, err)
+}
+
+func TestRequestIDSaved(t *testing.T) {
+	requestID, err := NewRequestID("X-Correlation-Id", "uuid", true)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		requestID.ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+			header, id := getRequestID(r)
+			assert.Equal(t, "X-Correlation-Id", header)
+			assert.Equal(t, r.Header.Get("X-Correlation-Id"), id)
+		})
+	})
+
+	// the header of another RequestID is not changed
+	other, err := NewRequestID("", "uuid", false)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	other.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {})
+	assert.NotEmpty(t, recorder.Header().Get("X-Request-ID"))
+	assert.Empty(t, recorder.Header().Get("X-Correlation-Id"))
+}
+
+// serveWithRequestID serves the request as the entry points do, with its request info and its ID in the X-Request-ID header.
+func serveWithRequestID(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID, _ := NewRequestID("X-Request-ID", "uuid", false)
+	NewRequestInfo("http").ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+		requestID.ServeHTTP(rw, r, next)
+	})
+}
+
+func TestULIDOrder(t *testing.T) {
+	first := newULID()
+	assert.Len(t, first, 26)
+	for i := 0; i < 100; i++ {
+		assert.True(t, newULID()[:10] >= first[:10], "the timestamp prefix sorts by creation time")
+	}
+}
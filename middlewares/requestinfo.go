@@ -0,0 +1,147 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/streamrail/concurrent-map"
+)
+
+// handledBy names what handled a request: its entry point, frontend, backend and the server of
+// the last attempt to forward it, with the number of attempts.
+type handledBy struct {
+	entryPoint string
+	frontend   string
+	backend    string
+	server     string
+	attempts   int
+}
+
+// requestInfo is what is known about a request in progress, shared by the middlewares handling it.
+type requestInfo struct {
+	mutex            sync.Mutex
+	requestURI       string // as sent by the client, before any prefix is stripped
+	requestIDHeader  string
+	requestID        string
+	handledBy        handledBy
+	username         string
+	cacheStatus      string
+	upstreamDuration time.Duration
+}
+
+// requestInfoHeader holds the key of the information of a request, set by RequestInfo on its way in,
+// overwriting any value sent by the client, and removed before forwarding the request to a backend.
+const requestInfoHeader = "X-Traefik-Reqid"
+
+var (
+	requestInfoCounter uint64       // Key of the last request info
+	requestInfos       = cmap.New() // Map of request info key to request info
+)
+
+// RequestInfo is the negroni handler keeping what handles each request of an entry point, from the
+// router onward, so that the access log, the metrics and the tracing see the same frontend, backend
+// and server. The frontend is recorded by SaveFrontend, the backend and server by SaveBackend.
+// Requests carry no context before Go 1.7: the information is found from a key generated for each
+// request, which the client cannot choose, in the requestInfoHeader header.
+type RequestInfo struct {
+	entryPointName string
+}
+
+// NewRequestInfo returns a new RequestInfo for the entry point named entryPointName.
+func NewRequestInfo(entryPointName string) *RequestInfo {
+	return &RequestInfo{entryPointName: entryPointName}
+}
+
+func (ri *RequestInfo) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	key := strconv.FormatUint(atomic.AddUint64(&requestInfoCounter, 1), 10)
+	requestInfos.Set(key, &requestInfo{requestURI: requestURI(r), handledBy: handledBy{entryPoint: ri.entryPointName}})
+	r.Header[requestInfoHeader] = []string{key}
+	defer func() {
+		requestInfos.Remove(key)
+		delete(r.Header, requestInfoHeader)
+	}()
+	next(rw, r)
+}
+
+// removeRequestInfo detaches a copy of a request, handled on its own, from the information of
+// the original request.
+func removeRequestInfo(r *http.Request) {
+	delete(r.Header, requestInfoHeader)
+}
+
+func getRequestInfo(r *http.Request) *requestInfo {
+	if key := r.Header[requestInfoHeader]; len(key) == 1 {
+		if info, ok := requestInfos.Get(key[0]); ok {
+			return info.(*requestInfo)
+		}
+	}
+	return nil
+}
+
+// getRequestURI returns the request-URI sent by the client, before any prefix is stripped.
+func getRequestURI(r *http.Request) string {
+	if info := getRequestInfo(r); info != nil {
+		return info.requestURI
+	}
+	return requestURI(r)
+}
+
+func requestURI(r *http.Request) string {
+	if len(r.RequestURI) == 0 {
+		return r.URL.RequestURI()
+	}
+	return r.RequestURI
+}
+
+// getRequestID returns the header holding the ID of the request and this ID, set by RequestID,
+// or nothing outside of a RequestInfo.
+func getRequestID(r *http.Request) (string, string) {
+	info := getRequestInfo(r)
+	if info == nil {
+		return "", ""
+	}
+	info.mutex.Lock()
+	defer info.mutex.Unlock()
+	return info.requestIDHeader, info.requestID
+}
+
+func saveRequestID(r *http.Request, header string, requestID string) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.requestIDHeader = header
+		info.requestID = requestID
+		info.mutex.Unlock()
+	}
+}
+
+// getHandledBy returns what handled the request so far, or nothing outside of a RequestInfo.
+func getHandledBy(r *http.Request) handledBy {
+	info := getRequestInfo(r)
+	if info == nil {
+		return handledBy{}
+	}
+	info.mutex.Lock()
+	defer info.mutex.Unlock()
+	return info.handledBy
+}
+
+func saveFrontend(r *http.Request, frontendName string) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.handledBy.frontend = frontendName
+		info.mutex.Unlock()
+	}
+}
+
+func saveServer(r *http.Request, backendName string, serverURL string) {
+	if info := getRequestInfo(r); info != nil {
+		info.mutex.Lock()
+		info.handledBy.backend = backendName
+		info.handledBy.server = serverURL
+		info.handledBy.attempts++
+		info.mutex.Unlock()
+	}
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestInfo(t *testing.T) {
+	var handled []handledBy
+	server := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handled = append(handled, getHandledBy(r))
+	})
+	// two frontends share the same backend
+	backend := NewSaveBackend(server, "backend1")
+	frontends := map[string]http.Handler{
+		"frontend1": NewSaveFrontend(backend, "frontend1"),
+		"frontend2": NewSaveFrontend(backend, "frontend2"),
+	}
+
+	serve := func(frontendName string, requestID string) {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/", nil)
+		req.Header.Set("X-Request-ID", requestID)
+		NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), req, frontends[frontendName].ServeHTTP)
+	}
+	serve("frontend2", "request1")
+	serve("frontend1", "request2")
+	assert.Equal(t, []handledBy{
+		{entryPoint: "http", frontend: "frontend2", backend: "backend1", server: "http://127.0.0.1/", attempts: 1},
+		{entryPoint: "http", frontend: "frontend1", backend: "backend1", server: "http://127.0.0.1/", attempts: 1},
+	}, handled)
+
+	// requests sharing the same ID, or sending the key of a request in progress, are kept apart
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/", nil)
+	req.Header.Set("X-Request-ID", "request1")
+	NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		saveFrontend(r, "frontend1")
+		inner, _ := http.NewRequest("GET", "http://127.0.0.1/", nil)
+		inner.Header.Set("X-Request-ID", "request1")
+		inner.Header.Set(requestInfoHeader, r.Header.Get(requestInfoHeader))
+		NewRequestInfo("http").ServeHTTP(httptest.NewRecorder(), inner, func(rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "request1", r.Header.Get("X-Request-ID"))
+			saveFrontend(r, "frontend2")
+		})
+		assert.Equal(t, "frontend1", getHandledBy(r).frontend)
+	})
+	assert.Empty(t, req.Header.Get(requestInfoHeader))
+	assert.Equal(t, 0, requestInfos.Count())
+}
@@ -0,0 +1,865 @@
+package middlewares
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+)
+
+// Cache statuses recorded in the X-Cache response header and in the access log.
+const (
+	cacheHit         = "HIT"
+	cacheMiss        = "MISS"
+	cacheStale       = "STALE"
+	cacheRevalidated = "REVALIDATED"
+	cacheBypass      = "BYPASS"
+)
+
+// ResponseCache serves the cacheable GET and HEAD responses of a frontend from a cache, according to
+// their Cache-Control, Expires and Vary headers. The entries are kept in memory, in least recently used
+// order, and the least recently used ones are moved to disk if a directory is set.
+// Expired entries are served while their stale-while-revalidate delay lasts, and refreshed in the background.
+// Entries with an ETag are revalidated with If-None-Match when they expire.
+type ResponseCache struct {
+	next         http.Handler
+	maxSize      int64
+	maxEntrySize int64
+	directory    string
+	maxDiskSize  int64
+	store        *cacheStore
+	onRequest    func(cacheStatus string)
+}
+
+// CacheStats holds the counters and the size of a ResponseCache.
+type CacheStats struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Stale       uint64 `json:"stale"`
+	Revalidated uint64 `json:"revalidated"`
+	Entries     int    `json:"entries"`
+	Size        int64  `json:"size"`
+	DiskEntries int    `json:"diskEntries"`
+	DiskSize    int64  `json:"diskSize"`
+}
+
+// cacheEntry is a cached response. Entries are never modified once stored, so that they can be
+// served without holding the lock of the store.
+type cacheEntry struct {
+	Key        string
+	Vary       map[string]string
+	Status     int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time
+	Expires    time.Time
+	StaleUntil time.Time
+}
+
+type cacheStore struct {
+	lock         sync.Mutex
+	memory       *cacheTier
+	disk         *cacheTier
+	revalidating map[*cacheEntry]bool
+	closed       bool
+	hits         uint64
+	misses       uint64
+	stale        uint64
+	revalidated  uint64
+}
+
+// cacheTier indexes its entries by key, with a variant per value of the Vary headers, in least recently used order.
+type cacheTier struct {
+	entries   map[string][]*list.Element
+	lru       *list.List
+	size      int64
+	maxSize   int64
+	directory string
+}
+
+// cacheItem is an element of the list of a cacheTier. On disk, only its metadata is kept, and its entry
+// is nil once written to its file.
+type cacheItem struct {
+	key     string
+	vary    map[string]string
+	size    int64
+	entry   *cacheEntry
+	file    string
+	removed bool
+}
+
+// cacheFiles are the files to write and remove once the lock of the store is released,
+// so that the disk is not accessed with the lock held.
+type cacheFiles struct {
+	writes  []*cacheItem
+	removes []string
+}
+
+var (
+	cacheDirectoriesLock sync.Mutex
+	cacheDirectories     = map[string]bool{} // Directories cleared since Traefik started
+)
+
+// NewResponseCache returns a new ResponseCache keeping up to maxSize bytes in memory and up to maxDiskSize
+// bytes in directory (no disk tier if empty). Responses bigger than maxEntrySize are not cached.
+// The responses left in directory by a previous run of Traefik are removed.
+func NewResponseCache(next http.Handler, maxSize int64, maxEntrySize int64, directory string, maxDiskSize int64) (*ResponseCache, error) {
+	store := &cacheStore{
+		memory:       newCacheTier(maxSize, ""),
+		revalidating: map[*cacheEntry]bool{},
+	}
+	if len(directory) > 0 {
+		if err := os.MkdirAll(directory, 0700); err != nil {
+			return nil, err
+		}
+		if err := clearCacheDirectory(directory); err != nil {
+			return nil, err
+		}
+		store.disk = newCacheTier(maxDiskSize, directory)
+	}
+	return &ResponseCache{
+		next:         next,
+		maxSize:      maxSize,
+		maxEntrySize: maxEntrySize,
+		directory:    directory,
+		maxDiskSize:  maxDiskSize,
+		store:        store,
+	}, nil
+}
+
+func newCacheTier(maxSize int64, directory string) *cacheTier {
+	return &cacheTier{entries: map[string][]*list.Element{}, lru: list.New(), maxSize: maxSize, directory: directory}
+}
+
+// clearCacheDirectory removes the cached responses from directory, the first time it is used since
+// Traefik started: they are not indexed, and would never be removed otherwise.
+func clearCacheDirectory(directory string) error {
+	cacheDirectoriesLock.Lock()
+	defer cacheDirectoriesLock.Unlock()
+	if cacheDirectories[directory] {
+		return nil
+	}
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		// only the files named like the cached responses are removed
+		if _, err := hex.DecodeString(file.Name()); err == nil && len(file.Name()) == 2*sha1.Size && file.Mode().IsRegular() {
+			if err := os.Remove(filepath.Join(directory, file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	cacheDirectories[directory] = true
+	return nil
+}
+
+// Inherit keeps the entries of the previous ResponseCache of the frontend, if it has the same limits.
+func (rc *ResponseCache) Inherit(previous *ResponseCache) {
+	if previous.maxSize == rc.maxSize && previous.maxEntrySize == rc.maxEntrySize && previous.directory == rc.directory && previous.maxDiskSize == rc.maxDiskSize {
+		rc.store = previous.store
+	}
+}
+
+// Inherited returns true if the ResponseCache keeps the entries of previous.
+func (rc *ResponseCache) Inherited(previous *ResponseCache) bool {
+	return rc.store == previous.store
+}
+
+// Close removes the responses stored on disk by the ResponseCache, once its entries are not kept
+// by the ResponseCache replacing it, or its frontend is removed. It stores no more responses on disk.
+func (rc *ResponseCache) Close() {
+	files := &cacheFiles{}
+	rc.store.lock.Lock()
+	rc.store.closed = true
+	if rc.store.disk != nil {
+		for rc.store.disk.lru.Len() > 0 {
+			files.remove(rc.store.disk.remove(rc.store.disk.lru.Back()))
+		}
+	}
+	rc.store.unlock(files)
+}
+
+// OnRequest sets the function called with the cache status of each request, for the metrics.
+func (rc *ResponseCache) OnRequest(onRequest func(cacheStatus string)) {
+	rc.onRequest = onRequest
+}
+
+// Stats returns the current counters and size of the ResponseCache.
+func (rc *ResponseCache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:        atomic.LoadUint64(&rc.store.hits),
+		Misses:      atomic.LoadUint64(&rc.store.misses),
+		Stale:       atomic.LoadUint64(&rc.store.stale),
+		Revalidated: atomic.LoadUint64(&rc.store.revalidated),
+	}
+	rc.store.lock.Lock()
+	defer rc.store.lock.Unlock()
+	stats.Entries = rc.store.memory.lru.Len()
+	stats.Size = rc.store.memory.size
+	if rc.store.disk != nil {
+		stats.DiskEntries = rc.store.disk.lru.Len()
+		stats.DiskSize = rc.store.disk.size
+	}
+	return stats
+}
+
+// Purge removes the entries cached for url, or for all the URLs starting with url if prefix is set,
+// and returns the number of removed entries. URLs are like http://host/path?query.
+func (rc *ResponseCache) Purge(url string, prefix bool) int {
+	files := &cacheFiles{}
+	rc.store.lock.Lock()
+	defer rc.store.unlock(files)
+	purged := 0
+	for _, tier := range []*cacheTier{rc.store.memory, rc.store.disk} {
+		if tier == nil {
+			continue
+		}
+		for key, elements := range tier.entries {
+			if key == url || prefix && strings.HasPrefix(key, url) {
+				for _, element := range elements {
+					files.remove(tier.remove(element))
+					purged++
+				}
+			}
+		}
+	}
+	return purged
+}
+
+func (rc *ResponseCache) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	key := cacheKey(r)
+	requestCacheControl := parseCacheControl(r.Header.Get("Cache-Control"))
+	if r.Method != "GET" && r.Method != "HEAD" {
+		rc.next.ServeHTTP(rw, r)
+		// the unsafe methods invalidate the cached responses of their URL
+		if r.Method != "OPTIONS" && r.Method != "TRACE" {
+			rc.Purge(key, false)
+		}
+		return
+	}
+	if _, noStore := requestCacheControl["no-store"]; noStore || len(r.Header.Get("Authorization")) > 0 || len(r.Header.Get("Range")) > 0 || len(r.Header.Get("Upgrade")) > 0 {
+		rc.requested(r, cacheBypass)
+		rw.Header().Set("X-Cache", cacheBypass)
+		rc.next.ServeHTTP(rw, r)
+		return
+	}
+
+	now := time.Now()
+	entry := rc.store.lookup(key, r.Header)
+	_, noCache := requestCacheControl["no-cache"]
+	if maxAge, ok := requestCacheControl["max-age"]; ok && maxAge == "0" || r.Header.Get("Pragma") == "no-cache" {
+		noCache = true
+	}
+	if entry != nil && !noCache {
+		if now.Before(entry.Expires) {
+			atomic.AddUint64(&rc.store.hits, 1)
+			rc.requested(r, cacheHit)
+			serveCacheEntry(rw, r, entry, cacheHit, now)
+			return
+		}
+		if now.Before(entry.StaleUntil) {
+			atomic.AddUint64(&rc.store.stale, 1)
+			rc.requested(r, cacheStale)
+			rc.revalidateInBackground(entry, r)
+			serveCacheEntry(rw, r, entry, cacheStale, now)
+			return
+		}
+	}
+
+	atomic.AddUint64(&rc.store.misses, 1)
+	etag := ""
+	if entry != nil {
+		etag = entry.Header.Get("ETag")
+	}
+	forwardedRequest := r
+	if r.Method == "HEAD" {
+		// HEAD requests are served by the cache, but only GET responses are stored
+		etag = ""
+	} else if len(etag) > 0 {
+		forwardedRequest = copyCacheRequest(r)
+		forwardedRequest.Header.Set("If-None-Match", etag)
+	}
+	crw := &cacheResponseWriter{rw: rw, header: http.Header{}, maxBodySize: rc.maxEntrySize, interceptNotModified: len(etag) > 0, cacheStatus: cacheMiss}
+	saveCacheStatusForLogger(r, cacheMiss)
+	rc.next.ServeHTTP(crw, forwardedRequest)
+	requestIDHeader, _ := getRequestID(r)
+	if crw.notModified {
+		atomic.AddUint64(&rc.store.revalidated, 1)
+		rc.requested(r, cacheRevalidated)
+		refreshed := rc.refresh(entry, storedCacheHeader(crw.header, requestIDHeader), now)
+		serveCacheEntry(rw, r, refreshed, cacheRevalidated, now)
+		return
+	}
+	rc.requested(r, cacheMiss)
+	if r.Method == "GET" && !crw.tooLarge {
+		rc.storeResponse(key, r.Header, crw.status, storedCacheHeader(crw.header, requestIDHeader), crw.body.Bytes(), now)
+	}
+}
+
+// requested records the cache status of a request for the access log and the metrics.
+func (rc *ResponseCache) requested(r *http.Request, cacheStatus string) {
+	saveCacheStatusForLogger(r, cacheStatus)
+	if rc.onRequest != nil {
+		rc.onRequest(cacheStatus)
+	}
+}
+
+// revalidateInBackground refreshes a stale entry, unless it is already being refreshed.
+func (rc *ResponseCache) revalidateInBackground(entry *cacheEntry, r *http.Request) {
+	rc.store.lock.Lock()
+	if rc.store.revalidating[entry] {
+		rc.store.lock.Unlock()
+		return
+	}
+	rc.store.revalidating[entry] = true
+	rc.store.lock.Unlock()
+
+	revalidationRequest := copyCacheRequest(r)
+	revalidationRequest.Method = "GET"
+	// the ID of the original request must not be shared by the background request
+	requestIDHeader, _ := getRequestID(r)
+	if len(requestIDHeader) > 0 {
+		revalidationRequest.Header.Set(requestIDHeader, newUUID())
+	}
+	removeRequestInfo(revalidationRequest)
+	if etag := entry.Header.Get("ETag"); len(etag) > 0 {
+		revalidationRequest.Header.Set("If-None-Match", etag)
+	} else {
+		revalidationRequest.Header.Del("If-None-Match")
+	}
+	revalidationRequest.Header.Del("If-Modified-Since")
+	safe.Go(func() {
+		defer func() {
+			rc.store.lock.Lock()
+			delete(rc.store.revalidating, entry)
+			rc.store.lock.Unlock()
+		}()
+		now := time.Now()
+		crw := &cacheResponseWriter{rw: &discardResponseWriter{header: http.Header{}}, header: http.Header{}, maxBodySize: rc.maxEntrySize, interceptNotModified: true}
+		rc.next.ServeHTTP(crw, revalidationRequest)
+		if crw.notModified {
+			rc.refresh(entry, storedCacheHeader(crw.header, requestIDHeader), now)
+		} else if !crw.tooLarge && crw.status != 0 {
+			rc.storeResponse(entry.Key, revalidationRequest.Header, crw.status, storedCacheHeader(crw.header, requestIDHeader), crw.body.Bytes(), now)
+		}
+	})
+}
+
+// refresh stores a copy of entry updated with the stored headers of a 304 response, and returns it.
+func (rc *ResponseCache) refresh(entry *cacheEntry, header http.Header, now time.Time) *cacheEntry {
+	refreshed := *entry
+	refreshed.Header = make(http.Header, len(entry.Header))
+	for name, values := range entry.Header {
+		refreshed.Header[name] = values
+	}
+	for name, values := range header {
+		refreshed.Header[name] = values
+	}
+	refreshed.StoredAt = now
+	refreshed.Expires, refreshed.StaleUntil, _ = cacheFreshness(refreshed.Header, now)
+	rc.store.put(&refreshed, rc.maxEntrySize)
+	return &refreshed
+}
+
+func (rc *ResponseCache) storeResponse(key string, requestHeader http.Header, status int, header http.Header, body []byte, now time.Time) {
+	if !cacheableStatus(status) || len(header.Get("Set-Cookie")) > 0 {
+		return
+	}
+	expires, staleUntil, ok := cacheFreshness(header, now)
+	if !ok {
+		return
+	}
+	vary := map[string]string{}
+	for _, varyHeader := range header["Vary"] {
+		for _, name := range strings.Split(varyHeader, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return
+			}
+			if len(name) > 0 {
+				vary[name] = requestHeader.Get(name)
+			}
+		}
+	}
+	rc.store.put(&cacheEntry{
+		Key:        key,
+		Vary:       vary,
+		Status:     status,
+		Header:     header,
+		Body:       body,
+		StoredAt:   now,
+		Expires:    expires,
+		StaleUntil: staleUntil,
+	}, rc.maxEntrySize)
+}
+
+// lookup returns the entry cached for key matching the Vary headers of the request, moving it to memory
+// if it was on disk, or nil. Expired entries which cannot be revalidated are removed.
+func (cs *cacheStore) lookup(key string, requestHeader http.Header) *cacheEntry {
+	files := &cacheFiles{}
+	cs.lock.Lock()
+	if element := cs.memory.find(key, requestHeader); element != nil {
+		defer cs.unlock(files)
+		item := element.Value.(*cacheItem)
+		if !usableCacheEntry(item.entry) {
+			cs.memory.remove(element)
+			return nil
+		}
+		cs.memory.lru.MoveToFront(element)
+		return item.entry
+	}
+	if cs.disk == nil {
+		cs.lock.Unlock()
+		return nil
+	}
+	element := cs.disk.find(key, requestHeader)
+	if element == nil {
+		cs.lock.Unlock()
+		return nil
+	}
+	// the entry leaves the disk index before its file is read, so that it is read only once
+	item := element.Value.(*cacheItem)
+	file := cs.disk.remove(element)
+	cs.lock.Unlock()
+
+	entry := item.entry
+	if entry == nil {
+		var err error
+		entry, err = readCacheEntry(file)
+		os.Remove(file)
+		if err != nil {
+			log.Errorf("Error reading cached response %s: %s", file, err)
+			return nil
+		}
+	}
+	if !usableCacheEntry(entry) {
+		return nil
+	}
+	cs.lock.Lock()
+	defer cs.unlock(files)
+	// the response may have been stored again meanwhile
+	if cs.memory.findExact(entry.Key, entry.Vary) == nil && cs.disk.findExact(entry.Key, entry.Vary) == nil {
+		cs.insert(entry, item.size, files)
+	}
+	return entry
+}
+
+// put stores entry in memory, replacing the entry with the same key and Vary headers.
+func (cs *cacheStore) put(entry *cacheEntry, maxEntrySize int64) {
+	size := int64(len(entry.Body) + len(entry.Key))
+	for name, values := range entry.Header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	if size > maxEntrySize || size > cs.memory.maxSize {
+		return
+	}
+	files := &cacheFiles{}
+	cs.lock.Lock()
+	defer cs.unlock(files)
+	for _, tier := range []*cacheTier{cs.memory, cs.disk} {
+		if tier == nil {
+			continue
+		}
+		if element := tier.findExact(entry.Key, entry.Vary); element != nil {
+			files.remove(tier.remove(element))
+		}
+	}
+	cs.insert(entry, size, files)
+}
+
+// insert adds entry to memory and moves the least recently used entries to disk, or drops them,
+// until the memory is within its size. It must be called with the lock held, the entries moved
+// to disk being added to files, to be written once it is released.
+func (cs *cacheStore) insert(entry *cacheEntry, size int64, files *cacheFiles) {
+	cs.memory.add(&cacheItem{key: entry.Key, vary: entry.Vary, size: size, entry: entry})
+	for cs.memory.size > cs.memory.maxSize {
+		element := cs.memory.lru.Back()
+		item := element.Value.(*cacheItem)
+		cs.memory.remove(element)
+		if cs.disk == nil || cs.closed || item.size > cs.disk.maxSize || !usableCacheEntry(item.entry) {
+			continue
+		}
+		// the entry is kept by its disk item until its file is written
+		diskItem := &cacheItem{key: item.key, vary: item.vary, size: item.size, entry: item.entry, file: cacheEntryFile(cs.disk.directory, item.entry)}
+		cs.disk.add(diskItem)
+		files.writes = append(files.writes, diskItem)
+		for cs.disk.size > cs.disk.maxSize {
+			files.remove(cs.disk.remove(cs.disk.lru.Back()))
+		}
+	}
+}
+
+// unlock releases the lock of the store, then writes and removes files.
+func (cs *cacheStore) unlock(files *cacheFiles) {
+	cs.lock.Unlock()
+	for _, file := range files.removes {
+		os.Remove(file)
+	}
+	for _, item := range files.writes {
+		err := writeCacheEntry(item.file, item.entry)
+		if err != nil {
+			log.Errorf("Error writing cached response of %s to disk: %s", item.key, err)
+		}
+		cs.lock.Lock()
+		removed := item.removed
+		if !removed && err != nil {
+			for _, element := range cs.disk.entries[item.key] {
+				if element.Value == item {
+					cs.disk.remove(element)
+					break
+				}
+			}
+		}
+		item.entry = nil
+		cs.lock.Unlock()
+		// the file of an item removed while it was written is not indexed anymore
+		if removed || err != nil {
+			os.Remove(item.file)
+		}
+	}
+}
+
+func (files *cacheFiles) remove(file string) {
+	if len(file) > 0 {
+		files.removes = append(files.removes, file)
+	}
+}
+
+func (ct *cacheTier) add(item *cacheItem) {
+	element := ct.lru.PushFront(item)
+	ct.entries[item.key] = append(ct.entries[item.key], element)
+	ct.size += item.size
+}
+
+// remove removes element from the tier, and returns the file to remove once the lock of the store
+// is released, if the entry was written to disk.
+func (ct *cacheTier) remove(element *list.Element) string {
+	item := element.Value.(*cacheItem)
+	item.removed = true
+	ct.lru.Remove(element)
+	ct.size -= item.size
+	elements := ct.entries[item.key]
+	for i, e := range elements {
+		if e == element {
+			elements = append(elements[:i], elements[i+1:]...)
+			break
+		}
+	}
+	if len(elements) == 0 {
+		delete(ct.entries, item.key)
+	} else {
+		ct.entries[item.key] = elements
+	}
+	if item.entry != nil {
+		return ""
+	}
+	return item.file
+}
+
+// find returns the element of key whose Vary headers match the request headers.
+func (ct *cacheTier) find(key string, requestHeader http.Header) *list.Element {
+	for _, element := range ct.entries[key] {
+		matches := true
+		for name, value := range element.Value.(*cacheItem).vary {
+			if requestHeader.Get(name) != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return element
+		}
+	}
+	return nil
+}
+
+// findExact returns the element of key with the same Vary headers.
+func (ct *cacheTier) findExact(key string, vary map[string]string) *list.Element {
+	for _, element := range ct.entries[key] {
+		itemVary := element.Value.(*cacheItem).vary
+		if len(itemVary) != len(vary) {
+			continue
+		}
+		matches := true
+		for name, value := range vary {
+			if itemValue, ok := itemVary[name]; !ok || itemValue != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return element
+		}
+	}
+	return nil
+}
+
+// cacheEntryFile returns the file of entry in directory.
+func cacheEntryFile(directory string, entry *cacheEntry) string {
+	varyNames := make([]string, 0, len(entry.Vary))
+	for name, value := range entry.Vary {
+		varyNames = append(varyNames, name+":"+value)
+	}
+	hash := sha1.Sum([]byte(entry.Key + "\n" + strings.Join(varyNames, "\n") + "\n" + strconv.FormatInt(entry.StoredAt.UnixNano(), 10)))
+	return filepath.Join(directory, hex.EncodeToString(hash[:]))
+}
+
+func writeCacheEntry(file string, entry *cacheEntry) error {
+	buffer := &bytes.Buffer{}
+	if err := gob.NewEncoder(buffer).Encode(entry); err != nil {
+		return err
+	}
+	return writeFile(file, buffer.Bytes())
+}
+
+func writeFile(file string, data []byte) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readCacheEntry(file string) (*cacheEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entry := &cacheEntry{}
+	if err := gob.NewDecoder(f).Decode(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func serveCacheEntry(rw http.ResponseWriter, r *http.Request, entry *cacheEntry, cacheStatus string, now time.Time) {
+	saveCacheStatusForLogger(r, cacheStatus)
+	for name, values := range entry.Header {
+		rw.Header()[name] = values
+	}
+	rw.Header().Set("Age", strconv.FormatInt(int64(now.Sub(entry.StoredAt)/time.Second), 10))
+	rw.Header().Set("X-Cache", cacheStatus)
+	if etag := entry.Header.Get("ETag"); len(etag) > 0 && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	rw.WriteHeader(entry.Status)
+	if r.Method != "HEAD" {
+		rw.Write(entry.Body)
+	}
+}
+
+// perResponseHeaders are the response headers which are not stored with the cached responses,
+// as they only apply to the response which set them.
+var perResponseHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "X-Cache"}
+
+// storedCacheHeader returns a copy of the headers of a response without the headers applying to this
+// response only, such as the request ID returned by the backend in requestIDHeader.
+func storedCacheHeader(header http.Header, requestIDHeader string) http.Header {
+	stored := make(http.Header, len(header))
+	for name, values := range header {
+		stored[name] = values
+	}
+	if len(requestIDHeader) > 0 {
+		stored.Del(requestIDHeader)
+	}
+	for _, name := range perResponseHeaders {
+		stored.Del(name)
+	}
+	return stored
+}
+
+// cacheKey returns the URL of the request, like http://host/path?query.
+func cacheKey(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func copyCacheRequest(r *http.Request) *http.Request {
+	request := new(http.Request)
+	*request = *r
+	request.Header = make(http.Header, len(r.Header))
+	for name, values := range r.Header {
+		request.Header[name] = values
+	}
+	return request
+}
+
+// usableCacheEntry checks that an entry can be served, possibly stale, or revalidated.
+func usableCacheEntry(entry *cacheEntry) bool {
+	return time.Now().Before(entry.StaleUntil) || len(entry.Header.Get("ETag")) > 0
+}
+
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// cacheFreshness returns until when a response is fresh and until when it can be served stale
+// while it is revalidated, or false if the response cannot be cached.
+func cacheFreshness(header http.Header, now time.Time) (time.Time, time.Time, bool) {
+	cacheControl := parseCacheControl(header.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cacheControl[directive]; ok {
+			return now, now, false
+		}
+	}
+	var ttl time.Duration
+	if seconds, ok := cacheControlSeconds(cacheControl, "s-maxage"); ok {
+		ttl = seconds
+	} else if seconds, ok := cacheControlSeconds(cacheControl, "max-age"); ok {
+		ttl = seconds
+	} else if expires := header.Get("Expires"); len(expires) > 0 {
+		expiresTime, err := http.ParseTime(expires)
+		if err != nil {
+			return now, now, false
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		ttl = expiresTime.Sub(date)
+	} else if len(header.Get("ETag")) == 0 {
+		return now, now, false
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	staleWhileRevalidate, _ := cacheControlSeconds(cacheControl, "stale-while-revalidate")
+	if ttl+staleWhileRevalidate == 0 && len(header.Get("ETag")) == 0 {
+		return now, now, false
+	}
+	return now.Add(ttl), now.Add(ttl + staleWhileRevalidate), true
+}
+
+func parseCacheControl(value string) map[string]string {
+	directives := map[string]string{}
+	for _, directive := range strings.Split(value, ",") {
+		nameValue := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+		if len(nameValue[0]) == 0 {
+			continue
+		}
+		name := strings.ToLower(nameValue[0])
+		if len(nameValue) == 2 {
+			directives[name] = strings.Trim(nameValue[1], `"`)
+		} else {
+			directives[name] = ""
+		}
+	}
+	return directives
+}
+
+func cacheControlSeconds(cacheControl map[string]string, directive string) (time.Duration, bool) {
+	value, ok := cacheControl[directive]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheResponseWriter forwards a response to the client while keeping a copy of it, up to maxBodySize.
+// If interceptNotModified is set, a 304 response is kept from the client.
+type cacheResponseWriter struct {
+	rw                   http.ResponseWriter
+	header               http.Header
+	status               int
+	body                 bytes.Buffer
+	maxBodySize          int64
+	tooLarge             bool
+	interceptNotModified bool
+	notModified          bool
+	cacheStatus          string
+}
+
+func (crw *cacheResponseWriter) Header() http.Header {
+	return crw.header
+}
+
+func (crw *cacheResponseWriter) WriteHeader(status int) {
+	if crw.status != 0 {
+		return
+	}
+	crw.status = status
+	if crw.interceptNotModified && status == http.StatusNotModified {
+		crw.notModified = true
+		return
+	}
+	for name, values := range crw.header {
+		crw.rw.Header()[name] = values
+	}
+	if len(crw.cacheStatus) > 0 {
+		crw.rw.Header().Set("X-Cache", crw.cacheStatus)
+	}
+	crw.rw.WriteHeader(status)
+}
+
+func (crw *cacheResponseWriter) Write(b []byte) (int, error) {
+	if crw.status == 0 {
+		crw.WriteHeader(http.StatusOK)
+	}
+	if crw.notModified {
+		return len(b), nil
+	}
+	if !crw.tooLarge {
+		if int64(crw.body.Len()+len(b)) > crw.maxBodySize {
+			crw.tooLarge = true
+			crw.body = bytes.Buffer{}
+		} else {
+			crw.body.Write(b)
+		}
+	}
+	return crw.rw.Write(b)
+}
+
+func (crw *cacheResponseWriter) Flush() {
+	if crw.status == 0 {
+		crw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := crw.rw.(http.Flusher); ok && !crw.notModified {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,268 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCacheTestRequest(url string, header ...string) *http.Request {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Host = req.URL.Host
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return req
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls int32
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/cached":
+			rw.Header().Set("Cache-Control", "public, max-age=60")
+		case "/private":
+			rw.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			rw.Header().Set("Cache-Control", "max-age=60")
+			rw.Header().Set("Vary", "Accept-Language")
+		}
+		fmt.Fprintf(rw, "%s %d", r.Header.Get("Accept-Language"), n)
+	})
+	cache, err := NewResponseCache(handler, 1024*1024, 1024, "", 0)
+	assert.NoError(t, err)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		cache.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve(newCacheTestRequest("http://test.localhost/cached"))
+	assert.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, " 1", recorder.Body.String())
+	recorder = serve(newCacheTestRequest("http://test.localhost/cached"))
+	assert.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, " 1", recorder.Body.String())
+	recorder = serve(newCacheTestRequest("http://test.localhost/cached", "Cache-Control", "no-cache"))
+	assert.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, " 2", recorder.Body.String())
+
+	serve(newCacheTestRequest("http://test.localhost/private"))
+	assert.Equal(t, "MISS", serve(newCacheTestRequest("http://test.localhost/private")).Header().Get("X-Cache"))
+
+	assert.Equal(t, "fr 5", serve(newCacheTestRequest("http://test.localhost/vary", "Accept-Language", "fr")).Body.String())
+	assert.Equal(t, "en 6", serve(newCacheTestRequest("http://test.localhost/vary", "Accept-Language", "en")).Body.String())
+	assert.Equal(t, "fr 5", serve(newCacheTestRequest("http://test.localhost/vary", "Accept-Language", "fr")).Body.String())
+
+	assert.Equal(t, 2, cache.Purge("http://test.localhost/vary", false))
+	assert.Equal(t, "MISS", serve(newCacheTestRequest("http://test.localhost/vary", "Accept-Language", "fr")).Header().Get("X-Cache"))
+	assert.Equal(t, 2, cache.Purge("http://test.localhost/", true))
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestResponseCacheRevalidation(t *testing.T) {
+	var calls int32
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.Header().Set("ETag", `"v1"`)
+		rw.Header().Set("Cache-Control", "max-age=0")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		rw.Write([]byte("content"))
+	})
+	cache, err := NewResponseCache(handler, 1024*1024, 1024, "", 0)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/etag"))
+	assert.Equal(t, "content", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/etag"))
+	assert.Equal(t, "REVALIDATED", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "content", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/etag", "If-None-Match", `"v1"`))
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	revalidated := make(chan bool, 1)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		rw.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(rw, "%d", n)
+		if n > 1 {
+			revalidated <- true
+		}
+	})
+	cache, err := NewResponseCache(handler, 1024*1024, 1024, "", 0)
+	assert.NoError(t, err)
+
+	cache.ServeHTTP(httptest.NewRecorder(), newCacheTestRequest("http://test.localhost/"))
+	recorder := httptest.NewRecorder()
+	cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/"))
+	assert.Equal(t, "STALE", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, "1", recorder.Body.String())
+
+	select {
+	case <-revalidated:
+	case <-time.After(time.Second):
+		t.Fatal("the stale response was not revalidated")
+	}
+	for i := 0; i < 100 && cache.Stats().Entries == 1; i++ {
+		recorder = httptest.NewRecorder()
+		cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/"))
+		if recorder.Body.String() == "2" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the revalidated response is not served")
+}
+
+func TestResponseCacheDiskTier(t *testing.T) {
+	directory, err := ioutil.TempDir("", "traefik-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Cache-Control", "max-age=60")
+		rw.Write([]byte(r.URL.Path))
+	})
+	// the memory holds a single entry
+	cache, err := NewResponseCache(handler, 100, 100, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/first", "/second"} {
+		cache.ServeHTTP(httptest.NewRecorder(), newCacheTestRequest("http://test.localhost"+path))
+	}
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 1, stats.DiskEntries)
+	files, _ := ioutil.ReadDir(directory)
+	assert.Len(t, files, 1)
+
+	recorder := httptest.NewRecorder()
+	cache.ServeHTTP(recorder, newCacheTestRequest("http://test.localhost/first"))
+	assert.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, "/first", recorder.Body.String())
+}
+
+func TestResponseCachePerResponseHeaders(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Cache-Control", "max-age=60")
+		rw.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+		rw.Header().Set("X-Custom", "value")
+		rw.Write([]byte("body"))
+	})
+	cache, err := NewResponseCache(handler, 1024*1024, 1024, "", 0)
+	assert.NoError(t, err)
+	var statuses []string
+	cache.OnRequest(func(cacheStatus string) {
+		statuses = append(statuses, cacheStatus)
+	})
+
+	recorder := httptest.NewRecorder()
+	serveWithRequestID(recorder, newCacheTestRequest("http://test.localhost/", "X-Request-ID", "request1"), cache.ServeHTTP)
+	assert.Equal(t, "request1", recorder.Header().Get("X-Request-ID"))
+	recorder = httptest.NewRecorder()
+	serveWithRequestID(recorder, newCacheTestRequest("http://test.localhost/", "X-Request-ID", "request2"), cache.ServeHTTP)
+	assert.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, []string{"request2"}, recorder.Header()["X-Request-Id"], "the request ID of the cached response is not replayed")
+	assert.Equal(t, "value", recorder.Header().Get("X-Custom"))
+	cache.ServeHTTP(httptest.NewRecorder(), newCacheTestRequest("http://test.localhost/", "Authorization", "Basic secret"))
+	assert.Equal(t, []string{cacheMiss, cacheHit, cacheBypass}, statuses)
+}
+
+func TestResponseCacheDiskCleanup(t *testing.T) {
+	directory, err := ioutil.TempDir("", "traefik-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	// files left by a previous run
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "0123456789abcdef0123456789abcdef01234567"), []byte("old"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "README"), []byte("not cached"), 0600))
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Cache-Control", "max-age=60")
+		rw.Write([]byte(r.URL.Path))
+	})
+	cache, err := NewResponseCache(handler, 100, 100, directory, 1024*1024)
+	assert.NoError(t, err)
+	files, _ := ioutil.ReadDir(directory)
+	assert.Len(t, files, 1)
+
+	for _, path := range []string{"/first", "/second"} {
+		cache.ServeHTTP(httptest.NewRecorder(), newCacheTestRequest("http://test.localhost"+path))
+	}
+	files, _ = ioutil.ReadDir(directory)
+	assert.Len(t, files, 2)
+
+	// the files of the previous cache are kept once it is inherited, removed once it is closed
+	next, err := NewResponseCache(handler, 100, 100, directory, 1024*1024)
+	assert.NoError(t, err)
+	next.Inherit(cache)
+	assert.True(t, next.Inherited(cache))
+	files, _ = ioutil.ReadDir(directory)
+	assert.Len(t, files, 2)
+	next.Close()
+	files, _ = ioutil.ReadDir(directory)
+	assert.Len(t, files, 1)
+	cache.ServeHTTP(httptest.NewRecorder(), newCacheTestRequest("http://test.localhost/third"))
+	files, _ = ioutil.ReadDir(directory)
+	assert.Len(t, files, 1, "a closed cache stores no more responses on disk")
+}
+
+func TestResponseCacheDiskWrites(t *testing.T) {
+	directory, err := ioutil.TempDir("", "traefik-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	cache, err := NewResponseCache(http.NotFoundHandler(), 100, 100, directory, 1024*1024)
+	assert.NoError(t, err)
+	newEntry := func(path string) *cacheEntry {
+		now := time.Now()
+		return &cacheEntry{Key: "http://test.localhost" + path, Vary: map[string]string{}, Status: http.StatusOK, Header: http.Header{}, Body: []byte(path), StoredAt: now, Expires: now.Add(time.Minute), StaleUntil: now.Add(time.Minute)}
+	}
+
+	// the files are written once the lock is released, the entries moved to disk being kept until then
+	files := &cacheFiles{}
+	cache.store.lock.Lock()
+	cache.store.insert(newEntry("/first"), 60, files)
+	cache.store.insert(newEntry("/second"), 60, files)
+	cache.store.lock.Unlock()
+	assert.Len(t, files.writes, 1)
+	entry := cache.store.lookup("http://test.localhost/first", http.Header{})
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "/first", string(entry.Body))
+	}
+	cache.store.lock.Lock()
+	cache.store.unlock(files)
+	dirFiles, _ := ioutil.ReadDir(directory)
+	assert.Len(t, dirFiles, 1, "the file of the entry moved back to memory while it was written is removed")
+	assert.Equal(t, 1, cache.Stats().DiskEntries)
+
+	entry = cache.store.lookup("http://test.localhost/second", http.Header{})
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "/second", string(entry.Body))
+	}
+}
@@ -2,19 +2,24 @@ package middlewares
 
 import (
 	"net/http"
+	"time"
 )
 
-// SaveBackend sends the backend name to the logger.
+// SaveBackend records the backend name and server URL of each attempt to forward the requests,
+// for the logger, metrics and tracing, and sends the time spent waiting for the server to the logger.
 type SaveBackend struct {
-	next http.Handler
+	next        http.Handler
+	backendName string
 }
 
 // NewSaveBackend creates a SaveBackend
-func NewSaveBackend(next http.Handler) *SaveBackend {
-	return &SaveBackend{next}
+func NewSaveBackend(next http.Handler, backendName string) *SaveBackend {
+	return &SaveBackend{next, backendName}
 }
 
 func (sb *SaveBackend) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	saveBackendNameForLogger(r, (*r.URL).String())
+	saveServer(r, sb.backendName, (*r.URL).String())
+	start := time.Now()
 	sb.next.ServeHTTP(rw, r)
+	saveUpstreamDurationForLogger(r, time.Since(start))
 }
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"net/http"
+)
+
+// SaveFrontend records the frontend name of the requests for the logger, metrics and tracing.
+type SaveFrontend struct {
+	next         http.Handler
+	frontendName string
+}
+
+// NewSaveFrontend creates a SaveFrontend
+func NewSaveFrontend(next http.Handler, frontendName string) *SaveFrontend {
+	return &SaveFrontend{next, frontendName}
+}
+
+func (sf *SaveFrontend) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	saveFrontend(r, sf.frontendName)
+	sf.next.ServeHTTP(rw, r)
+}
@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// SecurityOptions holds the security headers and checks applied by Security.
+type SecurityOptions struct {
+	AllowedHosts          []string
+	SSLRedirect           bool
+	SSLHost               string
+	STSSeconds            int64
+	STSIncludeSubdomains  bool
+	STSPreload            bool
+	FrameOptions          string
+	ContentTypeNosniff    bool
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+}
+
+// Security rejects the requests to hosts which are not allowed, redirects plain HTTP requests to HTTPS
+// and sets the security headers on the responses.
+type Security struct {
+	next            http.Handler
+	options         SecurityOptions
+	responseHeaders map[string]string
+}
+
+// NewSecurity returns a new Security forwarding the accepted requests to next.
+func NewSecurity(next http.Handler, options SecurityOptions) *Security {
+	responseHeaders := map[string]string{}
+	if len(options.FrameOptions) > 0 {
+		responseHeaders["X-Frame-Options"] = options.FrameOptions
+	}
+	if options.ContentTypeNosniff {
+		responseHeaders["X-Content-Type-Options"] = "nosniff"
+	}
+	if len(options.ContentSecurityPolicy) > 0 {
+		responseHeaders["Content-Security-Policy"] = options.ContentSecurityPolicy
+	}
+	if len(options.ReferrerPolicy) > 0 {
+		responseHeaders["Referrer-Policy"] = options.ReferrerPolicy
+	}
+	return &Security{next: next, options: options, responseHeaders: responseHeaders}
+}
+
+func (s *Security) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !s.allowedHost(r.Host) {
+		log.Debugf("Rejecting request to host %s, which is not allowed", r.Host)
+		http.Error(rw, "Bad Host", http.StatusBadRequest)
+		return
+	}
+	https := isHTTPS(r)
+	if s.options.SSLRedirect && !https {
+		host := r.Host
+		if len(s.options.SSLHost) > 0 {
+			host = s.options.SSLHost
+		}
+		http.Redirect(rw, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		return
+	}
+
+	stsHeader := ""
+	// browsers ignore the Strict-Transport-Security header on plain HTTP responses
+	if s.options.STSSeconds > 0 && https {
+		stsHeader = fmt.Sprintf("max-age=%d", s.options.STSSeconds)
+		if s.options.STSIncludeSubdomains {
+			stsHeader += "; includeSubDomains"
+		}
+		if s.options.STSPreload {
+			stsHeader += "; preload"
+		}
+	}
+	if len(s.responseHeaders) == 0 && len(stsHeader) == 0 {
+		s.next.ServeHTTP(rw, r)
+		return
+	}
+	s.next.ServeHTTP(&headersResponseWriter{ResponseWriter: rw, setHeaders: func(header http.Header) {
+		for name, value := range s.responseHeaders {
+			header.Set(name, value)
+		}
+		if len(stsHeader) > 0 {
+			header.Set("Strict-Transport-Security", stsHeader)
+		}
+	}}, r)
+}
+
+// isHTTPS returns true if the request was sent over HTTPS, to Træfɪk or to a proxy in front of it.
+// The X-Forwarded-Proto header of the proxy is only kept by ForwardedHeaders if the proxy is trusted.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	proto := strings.SplitN(r.Header.Get("X-Forwarded-Proto"), ",", 2)[0]
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+func (s *Security) allowedHost(host string) bool {
+	if len(s.options.AllowedHosts) == 0 {
+		return true
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	for _, allowedHost := range s.options.AllowedHosts {
+		if strings.EqualFold(allowedHost, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurity(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Frame-Options", "ALLOWALL")
+		rw.Write([]byte("ok"))
+	})
+	security := NewSecurity(handler, SecurityOptions{
+		AllowedHosts:          []string{"test.localhost"},
+		SSLRedirect:           true,
+		STSSeconds:            31536000,
+		STSIncludeSubdomains:  true,
+		FrameOptions:          "DENY",
+		ContentTypeNosniff:    true,
+		ContentSecurityPolicy: "default-src 'self'",
+		ReferrerPolicy:        "same-origin",
+	})
+
+	req, _ := http.NewRequest("GET", "http://other.localhost/", nil)
+	recorder := httptest.NewRecorder()
+	security.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	req, _ = http.NewRequest("GET", "http://test.localhost:80/foo?bar=1", nil)
+	recorder = httptest.NewRecorder()
+	security.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	assert.Equal(t, "https://test.localhost:80/foo?bar=1", recorder.Header().Get("Location"))
+
+	req.TLS = &tls.ConnectionState{}
+	recorder = httptest.NewRecorder()
+	security.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", recorder.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "default-src 'self'", recorder.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "same-origin", recorder.Header().Get("Referrer-Policy"))
+}
+
+func TestSecurityForwardedProto(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("ok"))
+	})
+	security := NewSecurity(handler, SecurityOptions{SSLRedirect: true, STSSeconds: 31536000})
+
+	req, _ := http.NewRequest("GET", "http://test.localhost/foo", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	security.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code, "the request was sent over HTTPS to the trusted proxy")
+	assert.Equal(t, "max-age=31536000", recorder.Header().Get("Strict-Transport-Security"))
+
+	req.Header.Set("X-Forwarded-Proto", "http")
+	recorder = httptest.NewRecorder()
+	security.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Strict-Transport-Security"))
+
+	// the header of an untrusted client is removed by ForwardedHeaders before reaching the frontend
+	forwardedHeaders, err := NewForwardedHeaders(false, []string{"10.0.0.1"})
+	assert.NoError(t, err)
+	for remoteAddr, expected := range map[string]int{"10.0.0.1:1234": http.StatusOK, "192.0.2.1:1234": http.StatusMovedPermanently} {
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.RemoteAddr = remoteAddr
+		recorder = httptest.NewRecorder()
+		forwardedHeaders.ServeHTTP(recorder, req, security.ServeHTTP)
+		assert.Equal(t, expected, recorder.Code, remoteAddr)
+	}
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// WeightedSplitter splits the traffic of a frontend between several backends according to their weights.
+type WeightedSplitter struct {
+	mutex      sync.Mutex
+	backends   []*splitBackend
+	overridden bool
+}
+
+type splitBackend struct {
+	name       string
+	handler    http.Handler
+	weight     int
+	configured int
+	current    int
+}
+
+// NewWeightedSplitter returns a new WeightedSplitter without any backend.
+func NewWeightedSplitter() *WeightedSplitter {
+	return &WeightedSplitter{}
+}
+
+// AddBackend adds a backend handler with its configured weight.
+func (ws *WeightedSplitter) AddBackend(name string, handler http.Handler, weight int) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.backends = append(ws.backends, &splitBackend{name: name, handler: handler, weight: weight, configured: weight})
+}
+
+func (ws *WeightedSplitter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	handler := ws.next()
+	if handler == nil {
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	handler.ServeHTTP(rw, r)
+}
+
+// next picks a backend using smooth weighted round robin, so that
+// backends are interleaved instead of being sent bursts of requests.
+func (ws *WeightedSplitter) next() http.Handler {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	total := 0
+	var best *splitBackend
+	for _, backend := range ws.backends {
+		backend.current += backend.weight
+		total += backend.weight
+		if backend.weight > 0 && (best == nil || backend.current > best.current) {
+			best = backend
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.current -= total
+	return best.handler
+}
+
+// Validate checks the configured weights: a backend with a weight of 0 gets no traffic,
+// but no weight can be negative and at least one must be positive.
+func (ws *WeightedSplitter) Validate() error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	weights := map[string]int{}
+	for _, backend := range ws.backends {
+		weights[backend.name] = backend.weight
+	}
+	return checkWeights(weights)
+}
+
+func checkWeights(weights map[string]int) error {
+	positive := false
+	for name, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("Invalid weight %d for backend %s", weight, name)
+		}
+		positive = positive || weight > 0
+	}
+	if !positive {
+		return errors.New("At least one backend must have a positive weight")
+	}
+	return nil
+}
+
+// Weights returns the current weight of each backend.
+func (ws *WeightedSplitter) Weights() map[string]int {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	weights := map[string]int{}
+	for _, backend := range ws.backends {
+		weights[backend.name] = backend.weight
+	}
+	return weights
+}
+
+// SetWeights changes the weights of the given backends at runtime, with the same rules as
+// Validate. Backends not present in weights keep their current weight.
+func (ws *WeightedSplitter) SetWeights(weights map[string]int) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	backends := map[string]*splitBackend{}
+	newWeights := map[string]int{}
+	for _, backend := range ws.backends {
+		backends[backend.name] = backend
+		newWeights[backend.name] = backend.weight
+	}
+	for name, weight := range weights {
+		if backends[name] == nil {
+			return fmt.Errorf("Unknown backend %s", name)
+		}
+		newWeights[name] = weight
+	}
+	if err := checkWeights(newWeights); err != nil {
+		return err
+	}
+	for name, weight := range weights {
+		backends[name].weight = weight
+	}
+	for _, backend := range ws.backends {
+		backend.current = 0
+	}
+	ws.overridden = true
+	return nil
+}
+
+// InheritWeights keeps the weights set at runtime on a previous splitter of the same
+// frontend, as long as the configured backends and weights did not change.
+func (ws *WeightedSplitter) InheritWeights(previous *WeightedSplitter) {
+	previous.mutex.Lock()
+	defer previous.mutex.Unlock()
+	if !previous.overridden || len(previous.backends) != len(ws.backends) {
+		return
+	}
+	weights := map[string]int{}
+	for i, backend := range previous.backends {
+		if ws.backends[i].name != backend.name || ws.backends[i].configured != backend.configured {
+			return
+		}
+		weights[backend.name] = backend.weight
+	}
+	ws.SetWeights(weights)
+}
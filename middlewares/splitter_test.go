@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSplitter(weights map[string]int, names ...string) (*WeightedSplitter, map[string]int) {
+	counts := map[string]int{}
+	splitter := NewWeightedSplitter()
+	for _, name := range names {
+		backendName := name
+		splitter.AddBackend(backendName, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			counts[backendName]++
+		}), weights[backendName])
+	}
+	return splitter, counts
+}
+
+func sendRequests(handler http.Handler, count int) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	for i := 0; i < count; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	}
+	return recorder
+}
+
+func TestWeightedSplitterDistribution(t *testing.T) {
+	splitter, counts := newTestSplitter(map[string]int{"v1": 90, "v2": 10}, "v1", "v2")
+	sendRequests(splitter, 100)
+	assert.Equal(t, map[string]int{"v1": 90, "v2": 10}, counts)
+
+	// requests are interleaved rather than sent in bursts
+	splitter, counts = newTestSplitter(map[string]int{"v1": 1, "v2": 1}, "v1", "v2")
+	sendRequests(splitter, 1)
+	sendRequests(splitter, 1)
+	assert.Equal(t, map[string]int{"v1": 1, "v2": 1}, counts)
+}
+
+func TestWeightedSplitterSetWeights(t *testing.T) {
+	splitter, counts := newTestSplitter(map[string]int{"v1": 90, "v2": 10}, "v1", "v2")
+
+	assert.NotNil(t, splitter.SetWeights(map[string]int{"v3": 10}))
+	assert.NotNil(t, splitter.SetWeights(map[string]int{"v1": -1}))
+	assert.Equal(t, map[string]int{"v1": 90, "v2": 10}, splitter.Weights())
+
+	assert.Nil(t, splitter.SetWeights(map[string]int{"v1": 0}))
+	assert.Equal(t, map[string]int{"v1": 0, "v2": 10}, splitter.Weights())
+	sendRequests(splitter, 10)
+	assert.Equal(t, map[string]int{"v2": 10}, counts)
+
+	// all the backends cannot be disabled
+	assert.NotNil(t, splitter.SetWeights(map[string]int{"v2": 0}))
+	assert.Equal(t, map[string]int{"v1": 0, "v2": 10}, splitter.Weights())
+	recorder := sendRequests(splitter, 1)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestWeightedSplitterValidate(t *testing.T) {
+	splitter, counts := newTestSplitter(map[string]int{"v1": 0, "v2": 1}, "v1", "v2")
+	assert.Nil(t, splitter.Validate())
+	sendRequests(splitter, 10)
+	assert.Equal(t, map[string]int{"v2": 10}, counts)
+
+	splitter, _ = newTestSplitter(map[string]int{"v1": 0, "v2": 0}, "v1", "v2")
+	assert.NotNil(t, splitter.Validate())
+
+	splitter, _ = newTestSplitter(map[string]int{"v1": -1, "v2": 1}, "v1", "v2")
+	assert.NotNil(t, splitter.Validate())
+}
+
+func TestWeightedSplitterInheritWeights(t *testing.T) {
+	previous, _ := newTestSplitter(map[string]int{"v1": 90, "v2": 10}, "v1", "v2")
+	splitter, _ := newTestSplitter(map[string]int{"v1": 90, "v2": 10}, "v1", "v2")
+	splitter.InheritWeights(previous)
+	assert.Equal(t, map[string]int{"v1": 90, "v2": 10}, splitter.Weights())
+
+	previous.SetWeights(map[string]int{"v1": 50, "v2": 50})
+	splitter, _ = newTestSplitter(map[string]int{"v1": 90, "v2": 10}, "v1", "v2")
+	splitter.InheritWeights(previous)
+	assert.Equal(t, map[string]int{"v1": 50, "v2": 50}, splitter.Weights())
+
+	// weights changed in the configuration take precedence
+	splitter, _ = newTestSplitter(map[string]int{"v1": 80, "v2": 20}, "v1", "v2")
+	splitter.InheritWeights(previous)
+	assert.Equal(t, map[string]int{"v1": 80, "v2": 20}, splitter.Weights())
+}
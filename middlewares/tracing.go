@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// Tracing records a span for each request served by next. The span is a child of the span
+// propagated in the request headers, which hold the new span while next serves the request:
+// the spans of the following handlers are its children, and the backends receive the span
+// of the forward call.
+type Tracing struct {
+	next          http.Handler
+	tracer        opentracing.Tracer
+	operationName string
+	kind          ext.SpanKindEnum
+	tags          opentracing.Tags
+	tagHandledBy  bool
+}
+
+// NewTracing returns a new Tracing recording spans named operationName, of the given kind
+// (none if empty) and with the given tags.
+func NewTracing(next http.Handler, tracer opentracing.Tracer, operationName string, kind ext.SpanKindEnum, tags opentracing.Tags) *Tracing {
+	return &Tracing{
+		next:          next,
+		tracer:        tracer,
+		operationName: operationName,
+		kind:          kind,
+		tags:          tags,
+	}
+}
+
+func (t *Tracing) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	t.serveHTTP(rw, r, t.next.ServeHTTP)
+}
+
+func (t *Tracing) serveHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	options := []opentracing.StartSpanOption{t.tags}
+	if parent, err := t.tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err == nil {
+		options = append(options, opentracing.ChildOf(parent))
+	} else if err != opentracing.ErrSpanContextNotFound {
+		log.Debugf("Error extracting span context from request to %s%s: %s", r.Host, r.RequestURI, err)
+	}
+	span := t.tracer.StartSpan(t.operationName, options...)
+	defer span.Finish()
+	if len(t.kind) > 0 {
+		ext.SpanKind.Set(span, t.kind)
+	}
+	ext.HTTPMethod.Set(span, r.Method)
+	ext.HTTPUrl.Set(span, r.URL.String())
+
+	// the propagated span is restored once next returns, so that the retries of a request
+	// are siblings and not children of the previous attempts
+	header := make(http.Header, len(r.Header))
+	for key, values := range r.Header {
+		header[key] = values
+	}
+	defer func() {
+		for key := range r.Header {
+			delete(r.Header, key)
+		}
+		for key, values := range header {
+			r.Header[key] = values
+		}
+	}()
+	// the injected headers replace those of the parent span, which some versions of opentracing-go
+	// would add to
+	injected := http.Header{}
+	if err := t.tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(injected)); err != nil {
+		log.Debugf("Error injecting span context in request to %s%s: %s", r.Host, r.RequestURI, err)
+	}
+	for key, values := range injected {
+		r.Header[key] = values
+	}
+
+	statusRw := &statusResponseWriter{ResponseWriter: rw}
+	next(statusRw, r)
+	if statusRw.status == 0 {
+		statusRw.status = http.StatusOK
+	}
+	ext.HTTPStatusCode.Set(span, uint16(statusRw.status))
+	if statusRw.status >= http.StatusInternalServerError {
+		ext.Error.Set(span, true)
+	}
+	if t.tagHandledBy {
+		handledBy := getHandledBy(r)
+		if len(handledBy.frontend) > 0 {
+			span.SetTag("frontend.name", handledBy.frontend)
+		}
+		if len(handledBy.backend) > 0 {
+			span.SetTag("backend.name", handledBy.backend)
+			span.SetTag("backend.server", handledBy.server)
+		}
+	}
+}
+
+// EntryPointTracing is the negroni handler recording the span of the requests of an entry point,
+// which is a child of the span sent by the client, if any. The span is tagged with the frontend,
+// backend and server which handled the request according to RequestInfo.
+type EntryPointTracing struct {
+	tracing *Tracing
+}
+
+// NewEntryPointTracing returns a new EntryPointTracing for the entry point named entryPointName.
+func NewEntryPointTracing(tracer opentracing.Tracer, entryPointName string) *EntryPointTracing {
+	tracing := NewTracing(nil, tracer, "entrypoint "+entryPointName, ext.SpanKindRPCServerEnum, opentracing.Tags{"entrypoint.name": entryPointName})
+	tracing.tagHandledBy = true
+	return &EntryPointTracing{tracing: tracing}
+}
+
+func (ept *EntryPointTracing) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ept.tracing.serveHTTP(rw, r, next)
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	tracer := mocktracer.New()
+	propagated := []http.Header{}
+	backend := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		header := http.Header{}
+		for key, values := range r.Header {
+			header[key] = values
+		}
+		propagated = append(propagated, header)
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+	// the forward call is attempted twice, like with a retry
+	forward := NewTracing(backend, tracer, "forward", ext.SpanKindRPCClientEnum, nil)
+	retry := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		forward.ServeHTTP(httptest.NewRecorder(), r)
+		forward.ServeHTTP(rw, r)
+	})
+	frontend := NewTracing(retry, tracer, "frontend frontend1", "", opentracing.Tags{"frontend.name": "frontend1"})
+	entryPoint := NewEntryPointTracing(tracer, "http")
+
+	req, _ := http.NewRequest("GET", "http://test.localhost/foo", nil)
+	entryPoint.ServeHTTP(httptest.NewRecorder(), req, frontend.ServeHTTP)
+
+	spans := tracer.FinishedSpans()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	firstForward, secondForward, frontendSpan, entryPointSpan := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, "entrypoint http", entryPointSpan.OperationName)
+	assert.Equal(t, 0, entryPointSpan.ParentID)
+	assert.Equal(t, entryPointSpan.SpanContext.SpanID, frontendSpan.ParentID)
+	assert.Equal(t, "frontend1", frontendSpan.Tag("frontend.name"))
+	assert.Equal(t, frontendSpan.SpanContext.SpanID, firstForward.ParentID)
+	assert.Equal(t, frontendSpan.SpanContext.SpanID, secondForward.ParentID, "the attempts are siblings")
+	assert.Equal(t, uint16(http.StatusBadGateway), secondForward.Tag(string(ext.HTTPStatusCode)))
+	assert.Equal(t, true, secondForward.Tag(string(ext.Error)))
+	assert.Equal(t, ext.SpanKindRPCClientEnum, secondForward.Tag(string(ext.SpanKind)))
+
+	// the backends receive the span of the forward call
+	spanContext, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(propagated[1]))
+	assert.NoError(t, err)
+	assert.Equal(t, secondForward.SpanContext.SpanID, spanContext.(mocktracer.MockSpanContext).SpanID)
+	for key, values := range propagated[1] {
+		assert.Len(t, values, 1, "the headers of %s replace those of the parent span", key)
+	}
+	assert.Empty(t, req.Header, "the request headers are restored")
+}
@@ -112,6 +112,14 @@ func (provider *ConsulCatalog) getEntryPoints(list string) []string {
 	return strings.Split(list, ",")
 }
 
+func (provider *ConsulCatalog) getBackends(list string) []types.WeightedBackend {
+	weightedBackends := parseWeightedBackends(list)
+	for i := range weightedBackends {
+		weightedBackends[i].Name = strings.ToLower(weightedBackends[i].Name)
+	}
+	return weightedBackends
+}
+
 func (provider *ConsulCatalog) getBackend(node *api.ServiceEntry) string {
 	return strings.ToLower(node.Service.Service)
 }
@@ -157,6 +165,24 @@ func (provider *ConsulCatalog) getAttribute(name string, tags []string, defaultV
 	return defaultValue
 }
 
+func (provider *ConsulCatalog) getHeaders(attributes []string) *types.Headers {
+	attribute := func(name string) string {
+		return provider.getAttribute("frontend.headers."+name, attributes, "")
+	}
+	return parseHeaders(attribute("customRequestHeaders"), attribute("customResponseHeaders"), attribute("removeRequestHeaders"), attribute("removeResponseHeaders"))
+}
+
+func (provider *ConsulCatalog) getMaintenance(attributes []string) *types.Maintenance {
+	attribute := func(name string) string {
+		return provider.getAttribute("frontend.maintenance."+name, attributes, "")
+	}
+	return parseMaintenance(attribute("enabled"), attribute("page"), attribute("retryAfter"), attribute("sourceRange"))
+}
+
+func (provider *ConsulCatalog) getBasicAuth(attributes []string) []string {
+	return parseBasicAuthUsers(provider.getAttribute("frontend.auth.basic", attributes, ""))
+}
+
 func (provider *ConsulCatalog) buildConfig(catalog []catalogUpdate) *types.Configuration {
 	var FuncMap = template.FuncMap{
 		"getBackend":        provider.getBackend,
@@ -165,6 +191,10 @@ func (provider *ConsulCatalog) buildConfig(catalog []catalogUpdate) *types.Confi
 		"getBackendAddress": provider.getBackendAddress,
 		"getAttribute":      provider.getAttribute,
 		"getEntryPoints":    provider.getEntryPoints,
+		"getBackends":       provider.getBackends,
+		"getHeaders":        provider.getHeaders,
+		"getMaintenance":    provider.getMaintenance,
+		"getBasicAuth":      provider.getBasicAuth,
 	}
 
 	allNodes := []*api.ServiceEntry{}
@@ -219,6 +249,7 @@ func (provider *ConsulCatalog) getNodes(index map[string][]string) ([]catalogUpd
 }
 
 func (provider *ConsulCatalog) watch(configurationChan chan<- types.ConfigMessage, stop chan bool) error {
+	providerLog := log.WithField("provider", "consul_catalog")
 	stopCh := make(chan struct{})
 	serviceCatalog := provider.watchServices(stopCh)
 
@@ -232,7 +263,7 @@ func (provider *ConsulCatalog) watch(configurationChan chan<- types.ConfigMessag
 			if !ok {
 				return errors.New("Consul service list nil")
 			}
-			log.Debug("List of services changed")
+			providerLog.Debug("List of services changed")
 			nodes, err := provider.getNodes(index)
 			if err != nil {
 				return err
@@ -249,6 +280,7 @@ func (provider *ConsulCatalog) watch(configurationChan chan<- types.ConfigMessag
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *ConsulCatalog) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", "consul_catalog")
 	config := api.DefaultConfig()
 	config.Address = provider.Endpoint
 	client, err := api.NewClient(config)
@@ -259,14 +291,14 @@ func (provider *ConsulCatalog) Provide(configurationChan chan<- types.ConfigMess
 
 	pool.Go(func(stop chan bool) {
 		notify := func(err error, time time.Duration) {
-			log.Errorf("Consul connection error %+v, retrying in %s", err, time)
+			providerLog.Errorf("Consul connection error %+v, retrying in %s", err, time)
 		}
 		worker := func() error {
 			return provider.watch(configurationChan, stop)
 		}
 		err := backoff.RetryNotify(worker, backoff.NewExponentialBackOff(), notify)
 		if err != nil {
-			log.Fatalf("Cannot connect to consul server %+v", err)
+			providerLog.Fatalf("Cannot connect to consul server %+v", err)
 		}
 	})
 
@@ -82,6 +82,35 @@ func TestConsulCatalogGetAttribute(t *testing.T) {
 	}
 }
 
+func TestConsulCatalogGetBasicAuth(t *testing.T) {
+	provider := &ConsulCatalog{
+		Domain: "localhost",
+	}
+
+	services := []struct {
+		tags     []string
+		expected []string
+	}{
+		{
+			tags:     []string{},
+			expected: []string{},
+		},
+		{
+			tags: []string{
+				"traefik.frontend.auth.basic=test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+			},
+			expected: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+		},
+	}
+
+	for _, e := range services {
+		actual := provider.getBasicAuth(e.tags)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %+v, got %+v", e.expected, actual)
+		}
+	}
+}
+
 func TestConsulCatalogGetBackendAddress(t *testing.T) {
 	provider := &ConsulCatalog{
 		Domain: "localhost",
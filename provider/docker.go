@@ -80,6 +80,7 @@ func (provider *Docker) createClient() (client.APIClient, error) {
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *Docker) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", "docker")
 	// TODO register this routine in pool, and watch for stop channel
 	safe.Go(func() {
 		operation := func() error {
@@ -87,14 +88,14 @@ func (provider *Docker) Provide(configurationChan chan<- types.ConfigMessage, po
 
 			dockerClient, err := provider.createClient()
 			if err != nil {
-				log.Errorf("Failed to create a client for docker, error: %s", err)
+				providerLog.Errorf("Failed to create a client for docker, error: %s", err)
 				return err
 			}
 			version, err := dockerClient.ServerVersion(context.Background())
-			log.Debugf("Docker connection established with docker %s (API %s)", version.Version, version.APIVersion)
+			providerLog.Debugf("Docker connection established with docker %s (API %s)", version.Version, version.APIVersion)
 			containers, err := listContainers(dockerClient)
 			if err != nil {
-				log.Errorf("Failed to list containers for docker, error %s", err)
+				providerLog.Errorf("Failed to list containers for docker, error %s", err)
 				return err
 			}
 			configuration := provider.loadDockerConfig(containers)
@@ -111,10 +112,10 @@ func (provider *Docker) Provide(configurationChan chan<- types.ConfigMessage, po
 				}
 				eventHandler := events.NewHandler(events.ByAction)
 				startStopHandle := func(m eventtypes.Message) {
-					log.Debugf("Docker event received %+v", m)
+					providerLog.Debugf("Docker event received %+v", m)
 					containers, err := listContainers(dockerClient)
 					if err != nil {
-						log.Errorf("Failed to list containers for docker, error %s", err)
+						providerLog.Errorf("Failed to list containers for docker, error %s", err)
 						// Call cancel to get out of the monitor
 						cancel()
 					}
@@ -146,11 +147,11 @@ func (provider *Docker) Provide(configurationChan chan<- types.ConfigMessage, po
 			return nil
 		}
 		notify := func(err error, time time.Duration) {
-			log.Errorf("Docker connection error %+v, retrying in %s", err, time)
+			providerLog.Errorf("Docker connection error %+v, retrying in %s", err, time)
 		}
 		err := backoff.RetryNotify(operation, backoff.NewExponentialBackOff(), notify)
 		if err != nil {
-			log.Fatalf("Cannot connect to docker server %+v", err)
+			providerLog.Fatalf("Cannot connect to docker server %+v", err)
 		}
 	})
 
@@ -167,6 +168,10 @@ func (provider *Docker) loadDockerConfig(containersInspected []dockertypes.Conta
 		"getPassHostHeader": provider.getPassHostHeader,
 		"getEntryPoints":    provider.getEntryPoints,
 		"getFrontendRule":   provider.getFrontendRule,
+		"getBackends":       provider.getBackends,
+		"getBasicAuth":      provider.getBasicAuth,
+		"getHeaders":        provider.getHeaders,
+		"getMaintenance":    provider.getMaintenance,
 		"replace":           replace,
 	}
 
@@ -291,6 +296,36 @@ func (provider *Docker) getEntryPoints(container dockertypes.ContainerJSON) []st
 	return []string{}
 }
 
+func (provider *Docker) getBackends(container dockertypes.ContainerJSON) []types.WeightedBackend {
+	if backends, err := getLabel(container, "traefik.frontend.backends"); err == nil {
+		return parseWeightedBackends(backends)
+	}
+	return []types.WeightedBackend{}
+}
+
+func (provider *Docker) getBasicAuth(container dockertypes.ContainerJSON) []string {
+	if basicAuth, err := getLabel(container, "traefik.frontend.auth.basic"); err == nil {
+		return parseBasicAuthUsers(basicAuth)
+	}
+	return []string{}
+}
+
+func (provider *Docker) getHeaders(container dockertypes.ContainerJSON) *types.Headers {
+	label := func(name string) string {
+		value, _ := getLabel(container, "traefik.frontend.headers."+name)
+		return value
+	}
+	return parseHeaders(label("customRequestHeaders"), label("customResponseHeaders"), label("removeRequestHeaders"), label("removeResponseHeaders"))
+}
+
+func (provider *Docker) getMaintenance(container dockertypes.ContainerJSON) *types.Maintenance {
+	label := func(name string) string {
+		value, _ := getLabel(container, "traefik.frontend.maintenance."+name)
+		return value
+	}
+	return parseMaintenance(label("enabled"), label("page"), label("retryAfter"), label("sourceRange"))
+}
+
 func getLabel(container dockertypes.ContainerJSON, label string) (string, error) {
 	for key, value := range container.Config.Labels {
 		if key == label {
@@ -437,6 +437,82 @@ func TestDockerGetPassHostHeader(t *testing.T) {
 	}
 }
 
+func TestDockerGetBackends(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []types.WeightedBackend
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: []types.WeightedBackend{},
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.backends": "v1:90,v2:10",
+					},
+				},
+			},
+			expected: []types.WeightedBackend{{Name: "v1", Weight: 90}, {Name: "v2", Weight: 10}},
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getBackends(e.container)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %+v, got %+v", e.expected, actual)
+		}
+	}
+}
+
+func TestDockerGetBasicAuth(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: []string{},
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.auth.basic": "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+					},
+				},
+			},
+			expected: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getBasicAuth(e.container)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %+v, got %+v", e.expected, actual)
+		}
+	}
+}
+
 func TestDockerGetLabel(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
@@ -853,6 +929,61 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			containers: []docker.ContainerJSON{
+				{
+					ContainerJSONBase: &docker.ContainerJSONBase{
+						Name: "test",
+					},
+					Config: &container.Config{
+						Labels: map[string]string{
+							"traefik.backend":           "v1",
+							"traefik.frontend.backends": "v1:90,v2:10",
+						},
+					},
+					NetworkSettings: &docker.NetworkSettings{
+						NetworkSettingsBase: docker.NetworkSettingsBase{
+							Ports: nat.PortMap{
+								"80/tcp": {},
+							},
+						},
+						Networks: map[string]*network.EndpointSettings{
+							"bridge": {
+								IPAddress: "127.0.0.1",
+							},
+						},
+					},
+				},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:        "backend-v1",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					Backends: []types.WeightedBackend{
+						{Name: "backend-v1", Weight: 90},
+						{Name: "backend-v2", Weight: 10},
+					},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-v1": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 1,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+		},
 	}
 
 	provider := &Docker{
@@ -20,15 +20,16 @@ type File struct {
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *File) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", "file")
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Error("Error creating file watcher", err)
+		providerLog.Error("Error creating file watcher", err)
 		return err
 	}
 
 	file, err := os.Open(provider.Filename)
 	if err != nil {
-		log.Error("Error opening file", err)
+		providerLog.Error("Error opening file", err)
 		return err
 	}
 	defer file.Close()
@@ -43,7 +44,7 @@ func (provider *File) Provide(configurationChan chan<- types.ConfigMessage, pool
 					return
 				case event := <-watcher.Events:
 					if strings.Contains(event.Name, file.Name()) {
-						log.Debug("File event:", event)
+						providerLog.Debug("File event:", event)
 						configuration := provider.loadFileConfig(file.Name())
 						if configuration != nil {
 							configurationChan <- types.ConfigMessage{
@@ -53,13 +54,13 @@ func (provider *File) Provide(configurationChan chan<- types.ConfigMessage, pool
 						}
 					}
 				case error := <-watcher.Errors:
-					log.Error("Watcher event error", error)
+					providerLog.Error("Watcher event error", error)
 				}
 			}
 		})
 		err = watcher.Add(filepath.Dir(file.Name()))
 		if err != nil {
-			log.Error("Error adding file watcher", err)
+			providerLog.Error("Error adding file watcher", err)
 			return err
 		}
 	}
@@ -55,6 +55,7 @@ func (provider *Kubernetes) createClient() (k8s.Client, error) {
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *Kubernetes) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", "kubernetes")
 	k8sClient, err := provider.createClient()
 	if err != nil {
 		return err
@@ -73,7 +74,7 @@ func (provider *Kubernetes) Provide(configurationChan chan<- types.ConfigMessage
 			for {
 				eventsChan, errEventsChan, err := k8sClient.WatchAll(stopWatch)
 				if err != nil {
-					log.Errorf("Error watching kubernetes events: %v", err)
+					providerLog.Errorf("Error watching kubernetes events: %v", err)
 					return err
 				}
 			Watch:
@@ -89,7 +90,7 @@ func (provider *Kubernetes) Provide(configurationChan chan<- types.ConfigMessage
 						}
 						return err
 					case event := <-eventsChan:
-						log.Debugf("Received event from kubenetes %+v", event)
+						providerLog.Debugf("Received event from kubenetes %+v", event)
 						templateObjects, err := provider.loadIngresses(k8sClient)
 						if err != nil {
 							return err
@@ -104,11 +105,11 @@ func (provider *Kubernetes) Provide(configurationChan chan<- types.ConfigMessage
 		}
 
 		notify := func(err error, time time.Duration) {
-			log.Errorf("Kubernetes connection error %+v, retrying in %s", err, time)
+			providerLog.Errorf("Kubernetes connection error %+v, retrying in %s", err, time)
 		}
 		err := backoff.RetryNotify(operation, backOff, notify)
 		if err != nil {
-			log.Fatalf("Cannot connect to Kubernetes server %+v", err)
+			providerLog.Fatalf("Cannot connect to Kubernetes server %+v", err)
 		}
 	})
 
@@ -159,6 +160,21 @@ func (provider *Kubernetes) loadIngresses(k8sClient k8s.Client) (*types.Configur
 						PassHostHeader: PassHostHeader,
 						Routes:         make(map[string]types.Route),
 					}
+					if basicAuth, ok := i.Annotations["traefik.frontend.auth.basic"]; ok {
+						templateObjects.Frontends[r.Host+pa.Path].Auth = &types.Auth{
+							Basic: &types.Basic{Users: parseBasicAuthUsers(basicAuth)},
+						}
+					}
+					templateObjects.Frontends[r.Host+pa.Path].Headers = parseHeaders(
+						i.Annotations["traefik.frontend.headers.customRequestHeaders"],
+						i.Annotations["traefik.frontend.headers.customResponseHeaders"],
+						i.Annotations["traefik.frontend.headers.removeRequestHeaders"],
+						i.Annotations["traefik.frontend.headers.removeResponseHeaders"])
+					templateObjects.Frontends[r.Host+pa.Path].Maintenance = parseMaintenance(
+						i.Annotations["traefik.frontend.maintenance.enabled"],
+						i.Annotations["traefik.frontend.maintenance.page"],
+						i.Annotations["traefik.frontend.maintenance.retryAfter"],
+						i.Annotations["traefik.frontend.maintenance.sourceRange"])
 				}
 				if _, exists := templateObjects.Frontends[r.Host+pa.Path].Routes[r.Host]; !exists {
 					templateObjects.Frontends[r.Host+pa.Path].Routes[r.Host] = types.Route{
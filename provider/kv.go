@@ -39,6 +39,7 @@ type KvTLS struct {
 }
 
 func (provider *Kv) watchKv(configurationChan chan<- types.ConfigMessage, prefix string, stop chan bool) error {
+	providerLog := log.WithField("provider", string(provider.storeType))
 	operation := func() error {
 		events, err := provider.kvclient.WatchTree(provider.Prefix, make(chan struct{}))
 		if err != nil {
@@ -64,7 +65,7 @@ func (provider *Kv) watchKv(configurationChan chan<- types.ConfigMessage, prefix
 	}
 
 	notify := func(err error, time time.Duration) {
-		log.Errorf("KV connection error: %+v, retrying in %s", err, time)
+		providerLog.Errorf("KV connection error: %+v, retrying in %s", err, time)
 	}
 	err := backoff.RetryNotify(operation, backoff.NewExponentialBackOff(), notify)
 	if err != nil {
@@ -74,6 +75,7 @@ func (provider *Kv) watchKv(configurationChan chan<- types.ConfigMessage, prefix
 }
 
 func (provider *Kv) provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", string(provider.storeType))
 	storeConfig := &store.Config{
 		ConnectionTimeout: 30 * time.Second,
 		Bucket:            "traefik",
@@ -122,7 +124,7 @@ func (provider *Kv) provide(configurationChan chan<- types.ConfigMessage, pool *
 			pool.Go(func(stop chan bool) {
 				err := provider.watchKv(configurationChan, provider.Prefix, stop)
 				if err != nil {
-					log.Errorf("Cannot watch KV store: %v", err)
+					providerLog.Errorf("Cannot watch KV store: %v", err)
 				}
 			})
 		}
@@ -134,7 +136,7 @@ func (provider *Kv) provide(configurationChan chan<- types.ConfigMessage, pool *
 		return nil
 	}
 	notify := func(err error, time time.Duration) {
-		log.Errorf("KV connection error: %+v, retrying in %s", err, time)
+		providerLog.Errorf("KV connection error: %+v, retrying in %s", err, time)
 	}
 	err := backoff.RetryNotify(operation, backoff.NewExponentialBackOff(), notify)
 	if err != nil {
@@ -151,10 +153,14 @@ func (provider *Kv) loadConfig() *types.Configuration {
 		strings.TrimSuffix(provider.get(provider.Prefix, provider.Prefix+"/alias"), "/"),
 	}
 	var KvFuncMap = template.FuncMap{
-		"List":     provider.list,
-		"Get":      provider.get,
-		"SplitGet": provider.splitGet,
-		"Last":     provider.last,
+		"List":             provider.list,
+		"Get":              provider.get,
+		"SplitGet":         provider.splitGet,
+		"Last":             provider.last,
+		"WeightedBackends": parseWeightedBackends,
+		"Headers":          parseHeaders,
+		"Maintenance":      parseMaintenance,
+		"BasicAuth":        parseBasicAuthUsers,
 	}
 
 	configuration, err := provider.getConfiguration("templates/kv.tmpl", KvFuncMap, templateObjects)
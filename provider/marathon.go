@@ -43,6 +43,7 @@ type lightMarathonClient interface {
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *Marathon) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	providerLog := log.WithField("provider", "marathon")
 	operation := func() error {
 		config := marathon.NewDefaultConfig()
 		config.URL = provider.Endpoint
@@ -58,14 +59,14 @@ func (provider *Marathon) Provide(configurationChan chan<- types.ConfigMessage,
 		}
 		client, err := marathon.NewClient(config)
 		if err != nil {
-			log.Errorf("Failed to create a client for marathon, error: %s", err)
+			providerLog.Errorf("Failed to create a client for marathon, error: %s", err)
 			return err
 		}
 		provider.marathonClient = client
 		update := make(marathon.EventsChannel, 5)
 		if provider.Watch {
 			if err := client.AddEventsListener(update, marathon.EVENTS_APPLICATIONS); err != nil {
-				log.Errorf("Failed to register for events, %s", err)
+				providerLog.Errorf("Failed to register for events, %s", err)
 				return err
 			}
 			pool.Go(func(stop chan bool) {
@@ -75,7 +76,7 @@ func (provider *Marathon) Provide(configurationChan chan<- types.ConfigMessage,
 					case <-stop:
 						return
 					case event := <-update:
-						log.Debug("Marathon event receveived", event)
+						providerLog.Debug("Marathon event receveived", event)
 						configuration := provider.loadMarathonConfig()
 						if configuration != nil {
 							configurationChan <- types.ConfigMessage{
@@ -96,11 +97,11 @@ func (provider *Marathon) Provide(configurationChan chan<- types.ConfigMessage,
 	}
 
 	notify := func(err error, time time.Duration) {
-		log.Errorf("Marathon connection error %+v, retrying in %s", err, time)
+		providerLog.Errorf("Marathon connection error %+v, retrying in %s", err, time)
 	}
 	err := backoff.RetryNotify(operation, backoff.NewExponentialBackOff(), notify)
 	if err != nil {
-		log.Fatalf("Cannot connect to Marathon server %+v", err)
+		providerLog.Fatalf("Cannot connect to Marathon server %+v", err)
 	}
 	return nil
 }
@@ -116,6 +117,10 @@ func (provider *Marathon) loadMarathonConfig() *types.Configuration {
 		"getEntryPoints":     provider.getEntryPoints,
 		"getFrontendRule":    provider.getFrontendRule,
 		"getFrontendBackend": provider.getFrontendBackend,
+		"getBackends":        provider.getBackends,
+		"getBasicAuth":       provider.getBasicAuth,
+		"getHeaders":         provider.getHeaders,
+		"getMaintenance":     provider.getMaintenance,
 		"replace":            replace,
 	}
 
@@ -326,6 +331,40 @@ func (provider *Marathon) getEntryPoints(application marathon.Application) []str
 	return []string{}
 }
 
+func (provider *Marathon) getBackends(application marathon.Application) []types.WeightedBackend {
+	if backends, err := provider.getLabel(application, "traefik.frontend.backends"); err == nil {
+		weightedBackends := parseWeightedBackends(backends)
+		for i := range weightedBackends {
+			weightedBackends[i].Name = replace("/", "-", weightedBackends[i].Name)
+		}
+		return weightedBackends
+	}
+	return []types.WeightedBackend{}
+}
+
+func (provider *Marathon) getBasicAuth(application marathon.Application) []string {
+	if basicAuth, err := provider.getLabel(application, "traefik.frontend.auth.basic"); err == nil {
+		return parseBasicAuthUsers(basicAuth)
+	}
+	return []string{}
+}
+
+func (provider *Marathon) getHeaders(application marathon.Application) *types.Headers {
+	label := func(name string) string {
+		value, _ := provider.getLabel(application, "traefik.frontend.headers."+name)
+		return value
+	}
+	return parseHeaders(label("customRequestHeaders"), label("customResponseHeaders"), label("removeRequestHeaders"), label("removeResponseHeaders"))
+}
+
+func (provider *Marathon) getMaintenance(application marathon.Application) *types.Maintenance {
+	label := func(name string) string {
+		value, _ := provider.getLabel(application, "traefik.frontend.maintenance."+name)
+		return value
+	}
+	return parseMaintenance(label("enabled"), label("page"), label("retryAfter"), label("sourceRange"))
+}
+
 // getFrontendRule returns the frontend rule for the specified application, using
 // it's label. It returns a default one (Host) if the label is not present.
 func (provider *Marathon) getFrontendRule(application marathon.Application) string {
@@ -3,10 +3,12 @@ package provider
 import (
 	"bytes"
 	"io/ioutil"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
 	"github.com/containous/traefik/autogen"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
@@ -77,3 +79,94 @@ func normalize(name string) string {
 	// get function
 	return strings.Join(strings.FieldsFunc(name, fargs), "-")
 }
+
+// parseWeightedBackends parses a comma separated list of backends with their weights,
+// like "v1:90,v2:10". A backend without weight gets a weight of 1.
+func parseWeightedBackends(value string) []types.WeightedBackend {
+	weightedBackends := []types.WeightedBackend{}
+	for _, backend := range strings.Split(value, ",") {
+		backend = strings.TrimSpace(backend)
+		if len(backend) == 0 {
+			continue
+		}
+		weightedBackend := types.WeightedBackend{Name: backend, Weight: 1}
+		if index := strings.LastIndex(backend, ":"); index != -1 {
+			weight, err := strconv.Atoi(backend[index+1:])
+			if err != nil {
+				log.Errorf("Invalid weight in weighted backend %s, skipping it", backend)
+				continue
+			}
+			weightedBackend = types.WeightedBackend{Name: backend[:index], Weight: weight}
+		}
+		weightedBackends = append(weightedBackends, weightedBackend)
+	}
+	return weightedBackends
+}
+
+// parseBasicAuthUsers parses a comma separated list of htpasswd users, like "user1:hash1,user2:hash2".
+func parseBasicAuthUsers(value string) []string {
+	return splitList(value)
+}
+
+// parseHeaders parses the headers configuration of a frontend from its labels. Custom headers are separated
+// by "||", like "X-Forwarded-Prefix:/api||X-Foo:bar", and removed headers by commas, like "Server,X-Powered-By".
+// It returns nil if no header is configured.
+func parseHeaders(customRequestHeaders, customResponseHeaders, removeRequestHeaders, removeResponseHeaders string) *types.Headers {
+	headers := &types.Headers{
+		CustomRequestHeaders:  parseCustomHeaders(customRequestHeaders),
+		CustomResponseHeaders: parseCustomHeaders(customResponseHeaders),
+		RemoveRequestHeaders:  splitList(removeRequestHeaders),
+		RemoveResponseHeaders: splitList(removeResponseHeaders),
+	}
+	if len(headers.CustomRequestHeaders) == 0 && len(headers.CustomResponseHeaders) == 0 && len(headers.RemoveRequestHeaders) == 0 && len(headers.RemoveResponseHeaders) == 0 {
+		return nil
+	}
+	return headers
+}
+
+func parseCustomHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, header := range strings.Split(value, "||") {
+		if len(strings.TrimSpace(header)) == 0 {
+			continue
+		}
+		nameValue := strings.SplitN(header, ":", 2)
+		if len(nameValue) != 2 {
+			log.Errorf("Invalid custom header %s, skipping it", header)
+			continue
+		}
+		headers[strings.TrimSpace(nameValue[0])] = strings.TrimSpace(nameValue[1])
+	}
+	return headers
+}
+
+// parseMaintenance parses the maintenance configuration of a frontend from its labels. The source
+// ranges are comma separated, like "10.0.0.0/8,192.168.1.2". It returns nil if no maintenance is configured.
+func parseMaintenance(enabled, page, retryAfter, sourceRange string) *types.Maintenance {
+	if len(enabled) == 0 && len(page) == 0 && len(retryAfter) == 0 && len(sourceRange) == 0 {
+		return nil
+	}
+	maintenance := &types.Maintenance{
+		Page:        page,
+		RetryAfter:  retryAfter,
+		SourceRange: splitList(sourceRange),
+	}
+	if len(enabled) > 0 {
+		var err error
+		if maintenance.Enabled, err = strconv.ParseBool(enabled); err != nil {
+			log.Errorf("Invalid maintenance state %s, disabling it", enabled)
+		}
+	}
+	return maintenance
+}
+
+// splitList splits a comma separated list, ignoring the empty elements.
+func splitList(value string) []string {
+	list := []string{}
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); len(element) > 0 {
+			list = append(list, element)
+		}
+	}
+	return list
+}
@@ -3,9 +3,12 @@ package provider
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"text/template"
+
+	"github.com/containous/traefik/types"
 )
 
 type myProvider struct {
@@ -169,6 +172,93 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+func TestParseWeightedBackends(t *testing.T) {
+	cases := []struct {
+		str      string
+		expected []types.WeightedBackend
+	}{
+		{
+			str:      "",
+			expected: []types.WeightedBackend{},
+		},
+		{
+			str:      "foo",
+			expected: []types.WeightedBackend{{Name: "foo", Weight: 1}},
+		},
+		{
+			str:      "v1:90, v2:10",
+			expected: []types.WeightedBackend{{Name: "v1", Weight: 90}, {Name: "v2", Weight: 10}},
+		},
+		{
+			str:      "v1:90,v2:foo,v3:0",
+			expected: []types.WeightedBackend{{Name: "v1", Weight: 90}, {Name: "v3", Weight: 0}},
+		},
+	}
+
+	for _, c := range cases {
+		actual := parseWeightedBackends(c.str)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("expected %+v, got %+v, for %q", c.expected, actual, c.str)
+		}
+	}
+}
+
+func TestParseBasicAuthUsers(t *testing.T) {
+	cases := []struct {
+		str      string
+		expected []string
+	}{
+		{
+			str:      "",
+			expected: []string{},
+		},
+		{
+			str:      "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/, test2:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=",
+			expected: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="},
+		},
+	}
+
+	for _, c := range cases {
+		actual := parseBasicAuthUsers(c.str)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("expected %+v, got %+v, for %q", c.expected, actual, c.str)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	if headers := parseHeaders("", "", " ", ""); headers != nil {
+		t.Fatalf("expected no headers, got %+v", headers)
+	}
+
+	headers := parseHeaders("X-Forwarded-Prefix:/api || X-Client:{client.ip}", "", "", "Server, X-Powered-By")
+	expected := &types.Headers{
+		CustomRequestHeaders:  map[string]string{"X-Forwarded-Prefix": "/api", "X-Client": "{client.ip}"},
+		CustomResponseHeaders: map[string]string{},
+		RemoveRequestHeaders:  []string{},
+		RemoveResponseHeaders: []string{"Server", "X-Powered-By"},
+	}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, headers)
+	}
+}
+
+func TestParseMaintenance(t *testing.T) {
+	if maintenance := parseMaintenance("", "", "", ""); maintenance != nil {
+		t.Fatalf("expected no maintenance, got %+v", maintenance)
+	}
+
+	maintenance := parseMaintenance("true", "", "600", "10.0.0.0/8, 192.168.1.2")
+	expected := &types.Maintenance{
+		Enabled:     true,
+		RetryAfter:  "600",
+		SourceRange: []string{"10.0.0.0/8", "192.168.1.2"},
+	}
+	if !reflect.DeepEqual(maintenance, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, maintenance)
+	}
+}
+
 func TestGetConfigurationReturnsCorrectMaxConnConfiguration(t *testing.T) {
 	templateFile, err := ioutil.TempFile("", "provider-configuration")
 	if err != nil {
@@ -4,18 +4,23 @@ Copyright
 package main
 
 import (
+	"crypto/sha1"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
-	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -32,11 +37,16 @@ import (
 	"github.com/containous/traefik/types"
 	"github.com/gorilla/mux"
 	"github.com/mailgun/manners"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"github.com/streamrail/concurrent-map"
 )
 
 var oxyLogger = &OxyLogger{}
 
+// forwardedHeadersRewriter keeps the X-Forwarded-For chains built by the entry points
+var forwardedHeadersRewriter = middlewares.NewForwardedHeadersRewriter()
+
 // Server is the reverse-proxy/load-balancer engine
 type Server struct {
 	serverEntryPoints          serverEntryPoints
@@ -47,8 +57,19 @@ type Server struct {
 	providers                  []provider.Provider
 	currentConfigurations      safe.Safe
 	globalConfiguration        GlobalConfiguration
+	requestIDMiddleware        *middlewares.RequestID
 	loggerMiddleware           *middlewares.Logger
+	tracer                     opentracing.Tracer
+	tracerCloser               io.Closer
+	metrics                    *middlewares.Metrics
+	metricsHandler             http.Handler
+	reportedServers            map[string]map[string]bool
+	events                     *eventBroker
+	history                    *configurationHistory
 	routinesPool               safe.Pool
+	backends                   safe.Safe
+	frontends                  safe.Safe
+	notFoundPage               notFoundPage
 }
 
 type serverEntryPoints map[string]*serverEntryPoint
@@ -73,11 +94,59 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 	server.signals = make(chan os.Signal, 1)
 	server.stopChan = make(chan bool, 1)
 	server.providers = []provider.Provider{}
-	signal.Notify(server.signals, syscall.SIGINT, syscall.SIGTERM)
+	server.configureSignals()
 	currentConfigurations := make(configs)
 	server.currentConfigurations.Set(currentConfigurations)
+	server.backends.Set(map[string]*cachedBackend{})
+	server.frontends.Set(map[string]*cachedFrontend{})
 	server.globalConfiguration = globalConfiguration
-	server.loggerMiddleware = middlewares.NewLogger(globalConfiguration.AccessLogsFile)
+	server.events = newEventBroker()
+	historySize := globalConfiguration.ConfigurationHistorySize
+	if historySize <= 0 {
+		historySize = 10
+	}
+	server.history = newConfigurationHistory(historySize)
+	if len(globalConfiguration.NotFoundPage) > 0 {
+		page, err := ioutil.ReadFile(globalConfiguration.NotFoundPage)
+		if err != nil {
+			log.Errorf("Error reading not found page %s: %s", globalConfiguration.NotFoundPage, err)
+		} else {
+			server.notFoundPage = page
+		}
+	}
+	requestID := globalConfiguration.RequestID
+	if requestID == nil {
+		requestID = &RequestID{}
+	}
+	requestIDMiddleware, err := middlewares.NewRequestID(requestID.Header, requestID.Format, requestID.IgnoreIncoming)
+	if err != nil {
+		log.Fatalf("Error creating request ID middleware: %s", err)
+	}
+	server.requestIDMiddleware = requestIDMiddleware
+	loggerMiddleware, err := newAccessLogger(globalConfiguration)
+	if err != nil {
+		log.Fatalf("Error creating access log: %s", err)
+	}
+	server.loggerMiddleware = loggerMiddleware
+	if globalConfiguration.Tracing != nil {
+		tracer, tracerCloser, err := newTracer(globalConfiguration.Tracing)
+		if err != nil {
+			log.Errorf("Error creating tracer, requests will not be traced: %s", err)
+		} else {
+			server.tracer = tracer
+			server.tracerCloser = tracerCloser
+		}
+	}
+	if globalConfiguration.Metrics != nil {
+		serverMetrics, metricsHandler, err := newMetrics(globalConfiguration.Metrics)
+		if err != nil {
+			log.Errorf("Error creating metrics, requests will not be measured: %s", err)
+		} else {
+			server.metrics = serverMetrics
+			server.metricsHandler = metricsHandler
+			server.loggerMiddleware.OnDrop(serverMetrics.AccessLogLineDropped)
+		}
+	}
 
 	return server
 }
@@ -113,12 +182,31 @@ func (server *Server) Close() {
 	close(server.signals)
 	close(server.stopChan)
 	server.loggerMiddleware.Close()
+	if server.tracerCloser != nil {
+		server.tracerCloser.Close()
+	}
+	if server.metrics != nil {
+		server.metrics.Close()
+	}
 }
 
 func (server *Server) startHTTPServers() {
 	server.serverEntryPoints = server.buildEntryPoints(server.globalConfiguration)
 	for newServerEntryPointName, newServerEntryPoint := range server.serverEntryPoints {
-		newsrv, err := server.prepareServer(newServerEntryPointName, newServerEntryPoint.httpRouter, server.globalConfiguration.EntryPoints[newServerEntryPointName], nil, server.loggerMiddleware, metrics)
+		serverMiddlewares := []negroni.Handler{}
+		forwardedHeaders, err := server.buildForwardedHeaders(server.globalConfiguration.EntryPoints[newServerEntryPointName].ForwardedHeaders)
+		if err != nil {
+			log.Fatalf("Error creating forwarded headers for entry point %s: %s", newServerEntryPointName, err)
+		}
+		serverMiddlewares = append(serverMiddlewares, forwardedHeaders, middlewares.NewRequestInfo(newServerEntryPointName), server.requestIDMiddleware)
+		if server.tracer != nil {
+			serverMiddlewares = append(serverMiddlewares, middlewares.NewEntryPointTracing(server.tracer, newServerEntryPointName))
+		}
+		serverMiddlewares = append(serverMiddlewares, server.loggerMiddleware.ForEntryPoint(newServerEntryPointName), metrics)
+		if server.metrics != nil {
+			serverMiddlewares = append(serverMiddlewares, server.metrics.ForEntryPoint(newServerEntryPointName))
+		}
+		newsrv, err := server.prepareServer(newServerEntryPointName, newServerEntryPoint.httpRouter, server.globalConfiguration.EntryPoints[newServerEntryPointName], nil, serverMiddlewares...)
 		if err != nil {
 			log.Fatal("Error preparing server: ", err)
 		}
@@ -139,16 +227,20 @@ func (server *Server) listenProviders(stop chan bool) {
 			if !ok {
 				return
 			}
+			providerLog := log.WithField("provider", configMsg.ProviderName)
 			jsonConf, _ := json.Marshal(configMsg.Configuration)
-			log.Debugf("Configuration received from provider %s: %s", configMsg.ProviderName, string(jsonConf))
+			providerLog.WithField("configuration", string(jsonConf)).Debug("Configuration received from provider")
+			diff := diffConfigurations(server.currentConfigurations.Get().(configs)[configMsg.ProviderName], configMsg.Configuration)
+			server.events.publish(event{Type: eventConfigurationReceived, Provider: configMsg.ProviderName, Diff: diff})
 			lastConfigs.Set(configMsg.ProviderName, &configMsg)
 			lastReceivedConfigurationValue := lastReceivedConfiguration.Get().(time.Time)
 			if time.Now().After(lastReceivedConfigurationValue.Add(time.Duration(server.globalConfiguration.ProvidersThrottleDuration))) {
-				log.Debugf("Last %s config received more than %s, OK", configMsg.ProviderName, server.globalConfiguration.ProvidersThrottleDuration)
+				providerLog.Debugf("Last config received more than %s, OK", server.globalConfiguration.ProvidersThrottleDuration)
 				// last config received more than n s ago
 				server.configurationValidatedChan <- configMsg
 			} else {
-				log.Debugf("Last %s config received less than %s, waiting...", configMsg.ProviderName, server.globalConfiguration.ProvidersThrottleDuration)
+				providerLog.Debugf("Last config received less than %s, waiting...", server.globalConfiguration.ProvidersThrottleDuration)
+				server.events.publish(event{Type: eventConfigurationThrottled, Provider: configMsg.ProviderName, Diff: diff})
 				server.routinesPool.Go(func(stop chan bool) {
 					select {
 					case <-stop:
@@ -156,7 +248,7 @@ func (server *Server) listenProviders(stop chan bool) {
 					case <-time.After(server.globalConfiguration.ProvidersThrottleDuration):
 						lastReceivedConfigurationValue := lastReceivedConfiguration.Get().(time.Time)
 						if time.Now().After(lastReceivedConfigurationValue.Add(time.Duration(server.globalConfiguration.ProvidersThrottleDuration))) {
-							log.Debugf("Waited for %s config, OK", configMsg.ProviderName)
+							providerLog.Debug("Waited for config, OK")
 							if lastConfig, ok := lastConfigs.Get(configMsg.ProviderName); ok {
 								server.configurationValidatedChan <- *lastConfig.(*types.ConfigMessage)
 							}
@@ -178,11 +270,13 @@ func (server *Server) listenConfigurations(stop chan bool) {
 			if !ok {
 				return
 			}
+			providerLog := log.WithField("provider", configMsg.ProviderName)
 			currentConfigurations := server.currentConfigurations.Get().(configs)
 			if configMsg.Configuration == nil {
-				log.Infof("Skipping empty Configuration for provider %s", configMsg.ProviderName)
+				providerLog.Info("Skipping empty configuration")
 			} else if reflect.DeepEqual(currentConfigurations[configMsg.ProviderName], configMsg.Configuration) {
-				log.Infof("Skipping same configuration for provider %s", configMsg.ProviderName)
+				providerLog.Info("Skipping same configuration")
+				server.configurationLoaded(configMsg, nil)
 			} else {
 				// Copy configurations to new map so we don't change current if LoadConfig fails
 				newConfigurations := make(configs)
@@ -191,21 +285,41 @@ func (server *Server) listenConfigurations(stop chan bool) {
 				}
 				newConfigurations[configMsg.ProviderName] = configMsg.Configuration
 
+				diff := diffConfigurations(currentConfigurations[configMsg.ProviderName], configMsg.Configuration)
 				newServerEntryPoints, err := server.loadConfig(newConfigurations, server.globalConfiguration)
 				if err == nil {
 					for newServerEntryPointName, newServerEntryPoint := range newServerEntryPoints {
 						server.serverEntryPoints[newServerEntryPointName].httpRouter.UpdateHandler(newServerEntryPoint.httpRouter.GetHandler())
-						log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
+						providerLog.WithField("entryPoint", newServerEntryPointName).Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 					}
 					server.currentConfigurations.Set(newConfigurations)
+					server.history.add(configMsg.ProviderName, configMsg.Configuration, diff)
+					server.events.publish(event{Type: eventConfigurationApplied, Provider: configMsg.ProviderName, Diff: diff})
+					server.configurationLoaded(configMsg, nil)
+					if server.metrics != nil {
+						server.metrics.ConfigReloaded(true)
+						server.reportServers()
+					}
 				} else {
-					log.Error("Error loading new configuration, aborted ", err)
+					providerLog.Errorf("Error loading new configuration, aborted: %s", err)
+					server.events.publish(event{Type: eventConfigurationRejected, Provider: configMsg.ProviderName, Diff: diff, Error: err.Error()})
+					server.configurationLoaded(configMsg, err)
+					if server.metrics != nil {
+						server.metrics.ConfigReloaded(false)
+					}
 				}
 			}
 		}
 	}
 }
 
+// configurationLoaded tells the web provider whether the configuration it sent was applied.
+func (server *Server) configurationLoaded(configMsg types.ConfigMessage, err error) {
+	if configMsg.ProviderName == "web" && server.globalConfiguration.Web != nil {
+		server.globalConfiguration.Web.configurationLoaded(configMsg.Configuration, err)
+	}
+}
+
 func (server *Server) configureProviders() {
 	// configure providers
 	if server.globalConfiguration.Docker != nil {
@@ -245,22 +359,70 @@ func (server *Server) startProviders() {
 	// start providers
 	for _, provider := range server.providers {
 		jsonConf, _ := json.Marshal(provider)
-		log.Infof("Starting provider %v %s", reflect.TypeOf(provider), jsonConf)
+		providerLog := log.WithField("providerType", reflect.TypeOf(provider).String())
+		providerLog.WithField("configuration", string(jsonConf)).Info("Starting provider")
 		currentProvider := provider
 		safe.Go(func() {
 			err := currentProvider.Provide(server.configurationChan, &server.routinesPool)
 			if err != nil {
-				log.Errorf("Error starting provider %s", err)
+				providerLog.Errorf("Error starting provider %s", err)
 			}
 		})
 	}
 }
 
-func (server *Server) listenSignals() {
-	sig := <-server.signals
-	log.Infof("I have to go... %+v", sig)
-	log.Info("Stopping server")
-	server.Stop()
+// reportServers records the servers of the backends as up, unless they are ejected, and the servers
+// removed from the configuration as down.
+func (server *Server) reportServers() {
+	backends := server.backends.Get().(map[string]*cachedBackend)
+	reportedServers := map[string]map[string]bool{}
+	for backendName, backend := range backends {
+		reportedServers[backendName] = map[string]bool{}
+		ejected := map[string]bool{}
+		if backend.outlierDetector != nil {
+			for _, ejectedServer := range backend.outlierDetector.Ejected() {
+				ejected[ejectedServer.URL] = true
+			}
+		}
+		for _, backendServer := range backend.configuration.Servers {
+			serverURL, err := url.Parse(backendServer.URL)
+			if err != nil {
+				continue
+			}
+			server.metrics.SetServerUp(backendName, serverURL.String(), !ejected[serverURL.String()])
+			reportedServers[backendName][serverURL.String()] = true
+		}
+	}
+	for backendName, serverURLs := range server.reportedServers {
+		for serverURL := range serverURLs {
+			if !reportedServers[backendName][serverURL] {
+				server.metrics.SetServerUp(backendName, serverURL, false)
+			}
+		}
+	}
+	server.reportedServers = reportedServers
+}
+
+// providerOfBackend returns the name of the provider of the current configuration defining the backend.
+func (server *Server) providerOfBackend(backendName string) string {
+	for providerName, configuration := range server.currentConfigurations.Get().(configs) {
+		if configuration != nil && configuration.Backends[backendName] != nil {
+			return providerName
+		}
+	}
+	return ""
+}
+
+// reopenLogs reopens the access and Traefik log files, once moved by a log rotation.
+func (server *Server) reopenLogs() {
+	if err := server.loggerMiddleware.Reopen(); err != nil {
+		log.Errorf("Error reopening access log %s: %s", server.globalConfiguration.AccessLogsFile, err)
+	}
+	if traefikLog != nil {
+		if err := traefikLog.Reopen(); err != nil {
+			log.Errorf("Error reopening Traefik log %s: %s", server.globalConfiguration.TraefikLogsFile, err)
+		}
+	}
 }
 
 // creates a TLS config that allows terminating HTTPS for multiple domains using SNI
@@ -288,6 +450,13 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 					}
 					return false
 				}
+				server.globalConfiguration.ACME.OnCertificate(func(domains []string, renewed bool) {
+					eventType := eventCertificateObtained
+					if renewed {
+						eventType = eventCertificateRenewed
+					}
+					server.events.publish(event{Type: eventType, Domains: domains})
+				})
 				err := server.globalConfiguration.ACME.CreateConfig(config, checkOnDemandDomain)
 				if err != nil {
 					return nil, err
@@ -327,26 +496,44 @@ func (server *Server) prepareServer(entryPointName string, router *middlewares.H
 	for _, middleware := range middlewares {
 		negroni.Use(middleware)
 	}
-	negroni.UseHandler(router)
+	if entryPoint.ForwardAuth != nil {
+		log.Debugf("Creating forward authentication for entry point %s to %s", entryPointName, entryPoint.ForwardAuth.Address)
+		forwardAuth, err := server.buildForwardAuth(entryPoint.ForwardAuth)
+		if err != nil {
+			log.Fatalf("Error creating forward authentication for entry point %s: %s", entryPointName, err)
+			return nil, err
+		}
+		negroni.Use(forwardAuth)
+	}
+	var handler http.Handler = router
+	if entryPoint.Auth != nil {
+		log.Debugf("Creating authentication for entry point %s", entryPointName)
+		authenticator, err := server.buildAuthenticator(router, entryPoint.Auth)
+		if err != nil {
+			log.Fatalf("Error creating authentication for entry point %s: %s", entryPointName, err)
+			return nil, err
+		}
+		handler = authenticator
+	}
+	negroni.UseHandler(handler)
 	tlsConfig, err := server.createTLSConfig(entryPointName, entryPoint.TLS, router)
 	if err != nil {
 		log.Fatalf("Error creating TLS config %s", err)
 		return nil, err
 	}
 
-	if oldServer == nil {
-		return manners.NewWithServer(
-			&http.Server{
-				Addr:      entryPoint.Address,
-				Handler:   negroni,
-				TLSConfig: tlsConfig,
-			}), nil
-	}
-	gracefulServer, err := oldServer.HijackListener(&http.Server{
+	httpServer := &http.Server{
 		Addr:      entryPoint.Address,
 		Handler:   negroni,
 		TLSConfig: tlsConfig,
-	}, tlsConfig)
+	}
+	if server.metrics != nil {
+		httpServer.ConnState = server.metrics.ConnStateHook(entryPointName)
+	}
+	if oldServer == nil {
+		return manners.NewWithServer(httpServer), nil
+	}
+	gracefulServer, err := oldServer.HijackListener(httpServer, tlsConfig)
 	if err != nil {
 		log.Fatalf("Error hijacking server %s", err)
 		return nil, err
@@ -371,26 +558,30 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 	serverEntryPoints := server.buildEntryPoints(globalConfiguration)
 	redirectHandlers := make(map[string]http.Handler)
 
-	backends := map[string]http.Handler{}
-	backend2FrontendMap := map[string]string{}
+	backends := map[string]*cachedBackend{}
+	frontends := map[string]*cachedFrontend{}
+	previousBackends := server.backends.Get().(map[string]*cachedBackend)
+	previousFrontends := server.frontends.Get().(map[string]*cachedFrontend)
+	// servers of reused backends are updated, and the state of replaced backends inherited,
+	// once the whole configuration is loaded
+	serverUpdates := []func(){}
 	for _, configuration := range configurations {
 		frontendNames := sortedFrontendNamesForConfig(configuration)
 		for _, frontendName := range frontendNames {
 			frontend := configuration.Frontends[frontendName]
 
-			log.Debugf("Creating frontend %s", frontendName)
-			fwd, _ := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader))
-			saveBackend := middlewares.NewSaveBackend(fwd)
+			frontendLog := log.WithField("frontend", frontendName)
+			frontendLog.Debug("Creating frontend")
 			// default endpoints if not defined in frontends
 			if len(frontend.EntryPoints) == 0 {
 				frontend.EntryPoints = globalConfiguration.DefaultEntryPoints
 			}
 			if len(frontend.EntryPoints) == 0 {
-				log.Errorf("No entrypoint defined for frontend %s, defaultEntryPoints:%s. Skipping it", frontendName, globalConfiguration.DefaultEntryPoints)
+				frontendLog.Errorf("No entrypoint defined for frontend, defaultEntryPoints:%s. Skipping it", globalConfiguration.DefaultEntryPoints)
 				continue
 			}
 			for _, entryPointName := range frontend.EntryPoints {
-				log.Debugf("Wiring frontend %s to entryPoint %s", frontendName, entryPointName)
+				frontendLog.WithField("entryPoint", entryPointName).Debug("Wiring frontend to entry point")
 				if _, ok := serverEntryPoints[entryPointName]; !ok {
 					return nil, errors.New("Undefined entrypoint: " + entryPointName)
 				}
@@ -400,7 +591,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 					if err != nil {
 						return nil, err
 					}
-					log.Debugf("Creating route %s %s", routeName, route.Rule)
+					frontendLog.Debugf("Creating route %s %s", routeName, route.Rule)
 				}
 				entryPoint := globalConfiguration.EntryPoints[entryPointName]
 				if entryPoint.Redirect != nil {
@@ -413,107 +604,544 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						redirectHandlers[entryPointName] = handler
 					}
 				} else {
-					if backends[frontend.Backend] == nil {
-						log.Debugf("Creating backend %s", frontend.Backend)
-						var lb http.Handler
-						rr, _ := roundrobin.New(saveBackend)
-						if configuration.Backends[frontend.Backend] == nil {
-							return nil, errors.New("Undefined backend: " + frontend.Backend)
-						}
-						lbMethod, err := types.NewLoadBalancerMethod(configuration.Backends[frontend.Backend].LoadBalancer)
-						if err != nil {
-							configuration.Backends[frontend.Backend].LoadBalancer = &types.LoadBalancer{Method: "wrr"}
-						}
-						switch lbMethod {
-						case types.Drr:
-							log.Debugf("Creating load-balancer drr")
-							rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
-							lb = rebalancer
-							for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-								url, err := url.Parse(server.URL)
-								if err != nil {
-									return nil, err
-								}
-								backend2FrontendMap[url.String()] = frontendName
-								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-								if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-									return nil, err
-								}
-							}
-						case types.Wrr:
-							log.Debugf("Creating load-balancer wrr")
-							lb = rr
-							for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-								url, err := url.Parse(server.URL)
-								if err != nil {
-									return nil, err
-								}
-								backend2FrontendMap[url.String()] = frontendName
-								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-								if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-									return nil, err
-								}
+					if frontends[frontendName] == nil {
+						for _, backendName := range frontendBackendNames(frontend) {
+							if backends[backendName] != nil {
+								frontendLog.WithField("backend", backendName).Debug("Reusing backend")
+								continue
 							}
-						}
-						maxConns := configuration.Backends[frontend.Backend].MaxConn
-						if maxConns != nil && maxConns.Amount != 0 {
-							extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
-							if err != nil {
-								return nil, err
-							}
-							log.Debugf("Creating loadd-balancer connlimit")
-							lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
+							backend, updateServers, err := server.loadBackend(configuration, backendName, frontend.PassHostHeader, previousBackends[backendName], globalConfiguration)
 							if err != nil {
 								return nil, err
 							}
-						}
-						// retry ?
-						if globalConfiguration.Retry != nil {
-							retries := len(configuration.Backends[frontend.Backend].Servers)
-							if globalConfiguration.Retry.Attempts > 0 {
-								retries = globalConfiguration.Retry.Attempts
-							}
-							maxMem := int64(2 * 1024 * 1024)
-							if globalConfiguration.Retry.MaxMem > 0 {
-								maxMem = globalConfiguration.Retry.MaxMem
-							}
-							lb, err = stream.New(lb,
-								stream.Logger(oxyLogger),
-								stream.Retry("IsNetworkError() && Attempts() < "+strconv.Itoa(retries)),
-								stream.MemRequestBodyBytes(maxMem),
-								stream.MaxRequestBodyBytes(maxMem),
-								stream.MemResponseBodyBytes(maxMem),
-								stream.MaxResponseBodyBytes(maxMem))
-							log.Debugf("Creating retries max attempts %d", retries)
-							if err != nil {
-								return nil, err
+							if updateServers != nil {
+								serverUpdates = append(serverUpdates, updateServers)
 							}
+							backends[backendName] = backend
 						}
-
-						var negroni = negroni.New()
-						if configuration.Backends[frontend.Backend].CircuitBreaker != nil {
-							log.Debugf("Creating circuit breaker %s", configuration.Backends[frontend.Backend].CircuitBreaker.Expression)
-							negroni.Use(middlewares.NewCircuitBreaker(lb, configuration.Backends[frontend.Backend].CircuitBreaker.Expression, cbreaker.Logger(oxyLogger)))
-						} else {
-							negroni.UseHandler(lb)
+						cachedFrontend, err := server.loadFrontend(frontendName, frontend, backends, previousFrontends[frontendName])
+						if err != nil {
+							return nil, err
 						}
-						backends[frontend.Backend] = negroni
-					} else {
-						log.Debugf("Reusing backend %s", frontend.Backend)
+						frontends[frontendName] = cachedFrontend
 					}
-					server.wireFrontendBackend(newServerRoute, backends[frontend.Backend])
+					server.wireFrontendBackend(newServerRoute, frontends[frontendName].handler)
 				}
 				err := newServerRoute.route.GetError()
 				if err != nil {
-					log.Errorf("Error building route: %s", err)
+					frontendLog.Errorf("Error building route: %s", err)
 				}
 			}
 		}
 	}
-	middlewares.SetBackend2FrontendMap(&backend2FrontendMap)
+	for _, updateServers := range serverUpdates {
+		updateServers()
+	}
+	// the responses cached on disk by the frontends removed, or whose cache was not inherited, are removed,
+	// and the connections to the authentication services of the frontends replaced are closed once idle
+	for frontendName, previous := range previousFrontends {
+		frontend, ok := frontends[frontendName]
+		if previous.forwardAuth != nil && (!ok || frontend.forwardAuth != previous.forwardAuth) {
+			previous.forwardAuth.CloseIdleConnections()
+		}
+		if previous.responseCache == nil {
+			continue
+		}
+		if !ok || frontend.responseCache == nil || !frontend.responseCache.Inherited(previous.responseCache) {
+			previous.responseCache.Close()
+		}
+	}
+	server.backends.Set(backends)
+	server.frontends.Set(frontends)
 	return serverEntryPoints, nil
 }
 
+// loadFrontend builds the handler of a frontend on top of its backends, or reuses the previous
+// one if neither the frontend configuration nor its backends changed.
+func (server *Server) loadFrontend(frontendName string, frontend *types.Frontend, backends map[string]*cachedBackend, previous *cachedFrontend) (*cachedFrontend, error) {
+	backendHandlers := []http.Handler{}
+	for _, backendName := range frontendBackendNames(frontend) {
+		backendHandlers = append(backendHandlers, backends[backendName].handler)
+	}
+	if previous != nil && previous.reusable(frontend, backendHandlers) {
+		log.WithField("frontend", frontendName).Debug("Reusing frontend from the previous configuration")
+		return previous, nil
+	}
+
+	cachedFrontend := &cachedFrontend{configuration: *frontend, backends: backendHandlers}
+	var handler http.Handler
+	if len(frontend.Backends) > 0 {
+		cachedFrontend.splitter = middlewares.NewWeightedSplitter()
+		for _, weightedBackend := range frontend.Backends {
+			log.Debugf("Splitting frontend %s to backend %s with weight %d", frontendName, weightedBackend.Name, weightedBackend.Weight)
+			cachedFrontend.splitter.AddBackend(weightedBackend.Name, backends[weightedBackend.Name].handler, weightedBackend.Weight)
+		}
+		if err := cachedFrontend.splitter.Validate(); err != nil {
+			return nil, fmt.Errorf("Invalid weights of frontend %s: %s", frontendName, err)
+		}
+		if previous != nil && previous.splitter != nil {
+			cachedFrontend.splitter.InheritWeights(previous.splitter)
+		}
+		handler = cachedFrontend.splitter
+	} else {
+		handler = backends[frontend.Backend].handler
+	}
+	if frontend.Cache != nil {
+		log.Debugf("Creating response cache for frontend %s", frontendName)
+		responseCache, err := server.buildResponseCache(handler, frontendName, frontend.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cache of frontend %s: %s", frontendName, err)
+		}
+		if previous != nil && previous.responseCache != nil {
+			responseCache.Inherit(previous.responseCache)
+		}
+		cachedFrontend.responseCache = responseCache
+		handler = responseCache
+	}
+	if frontend.Mirror != nil {
+		percent := 100
+		if frontend.Mirror.Percent > 0 {
+			percent = frontend.Mirror.Percent
+		}
+		maxBodySize := int64(2 * 1024 * 1024)
+		if frontend.Mirror.MaxBodySize > 0 {
+			maxBodySize = frontend.Mirror.MaxBodySize
+		}
+		maxInFlight := 100
+		if frontend.Mirror.MaxInFlight > 0 {
+			maxInFlight = frontend.Mirror.MaxInFlight
+		}
+		log.Debugf("Creating mirror of %d%% of frontend %s to backend %s", percent, frontendName, frontend.Mirror.Backend)
+		cachedFrontend.mirror = middlewares.NewMirror(handler, backends[frontend.Mirror.Backend].handler, percent, maxBodySize, maxInFlight)
+		handler = cachedFrontend.mirror
+	}
+	if len(frontend.Errors) > 0 {
+		errorPages := middlewares.NewErrorPages(handler)
+		for _, errorPageName := range sortedErrorPageNames(frontend) {
+			errorPage := frontend.Errors[errorPageName]
+			log.Debugf("Creating error page %s for status %v of frontend %s from backend %s at %s", errorPageName, errorPage.Status, frontendName, errorPage.Backend, errorPage.Query)
+			if err := errorPages.AddPage(errorPage.Status, backends[errorPage.Backend].handler, errorPage.Query); err != nil {
+				return nil, fmt.Errorf("Invalid error page %s of frontend %s: %s", errorPageName, frontendName, err)
+			}
+		}
+		cachedFrontend.errorPages = errorPages
+		handler = errorPages
+	}
+	if frontend.Headers != nil {
+		log.Debugf("Creating headers for frontend %s", frontendName)
+		handler = middlewares.NewHeaders(handler, frontendName, frontend.Headers.CustomRequestHeaders, frontend.Headers.CustomResponseHeaders, frontend.Headers.RemoveRequestHeaders, frontend.Headers.RemoveResponseHeaders)
+	}
+	if frontend.Auth != nil {
+		log.Debugf("Creating authentication for frontend %s", frontendName)
+		authenticator, err := server.buildAuthenticator(handler, frontend.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid authentication of frontend %s: %s", frontendName, err)
+		}
+		handler = authenticator
+	}
+	if frontend.ForwardAuth != nil {
+		log.Debugf("Creating forward authentication for frontend %s to %s", frontendName, frontend.ForwardAuth.Address)
+		forwardAuth, err := server.buildForwardAuth(frontend.ForwardAuth)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid forward authentication of frontend %s: %s", frontendName, err)
+		}
+		cachedFrontend.forwardAuth = forwardAuth
+		negroni := negroni.New()
+		negroni.Use(forwardAuth)
+		negroni.UseHandler(handler)
+		handler = negroni
+	}
+	// the rate limit applies before the authentication, so that failed attempts count too
+	if frontend.RateLimit != nil {
+		log.Debugf("Creating rate limiter for frontend %s", frontendName)
+		rateLimiter, err := server.buildRateLimiter(handler, frontend.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil && previous.rateLimiter != nil {
+			rateLimiter.Inherit(previous.rateLimiter)
+		}
+		cachedFrontend.rateLimiter = rateLimiter
+		handler = rateLimiter
+	}
+	// every frontend can be put in maintenance at runtime, even without maintenance configuration
+	maintenance, err := server.buildMaintenance(handler, frontend.Maintenance)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid maintenance of frontend %s: %s", frontendName, err)
+	}
+	if previous != nil && previous.maintenance != nil {
+		maintenance.Inherit(previous.maintenance)
+	}
+	cachedFrontend.maintenance = maintenance
+	handler = maintenance
+	// preflight requests carry no credentials, they are answered before the authentication
+	if frontend.CORS != nil {
+		log.Debugf("Creating CORS policy for frontend %s", frontendName)
+		cors, err := middlewares.NewCORS(handler, middlewares.CORSOptions{
+			AllowedOrigins:   frontend.CORS.AllowedOrigins,
+			AllowedMethods:   frontend.CORS.AllowedMethods,
+			AllowedHeaders:   frontend.CORS.AllowedHeaders,
+			ExposedHeaders:   frontend.CORS.ExposedHeaders,
+			AllowCredentials: frontend.CORS.AllowCredentials,
+			MaxAge:           frontend.CORS.MaxAge,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CORS policy of frontend %s: %s", frontendName, err)
+		}
+		handler = cors
+	}
+	if frontend.Security != nil {
+		log.Debugf("Creating security headers for frontend %s", frontendName)
+		handler = middlewares.NewSecurity(handler, middlewares.SecurityOptions{
+			AllowedHosts:          frontend.Security.AllowedHosts,
+			SSLRedirect:           frontend.Security.SSLRedirect,
+			SSLHost:               frontend.Security.SSLHost,
+			STSSeconds:            frontend.Security.STSSeconds,
+			STSIncludeSubdomains:  frontend.Security.STSIncludeSubdomains,
+			STSPreload:            frontend.Security.STSPreload,
+			FrameOptions:          frontend.Security.FrameOptions,
+			ContentTypeNosniff:    frontend.Security.ContentTypeNosniff,
+			ContentSecurityPolicy: frontend.Security.ContentSecurityPolicy,
+			ReferrerPolicy:        frontend.Security.ReferrerPolicy,
+		})
+	}
+	if server.tracer != nil {
+		handler = middlewares.NewTracing(handler, server.tracer, "frontend "+frontendName, "", opentracing.Tags{"frontend.name": frontendName})
+	}
+	handler = middlewares.NewSaveFrontend(handler, frontendName)
+	cachedFrontend.handler = handler
+	return cachedFrontend, nil
+}
+
+// loadBackend builds the handler of a backend, or reuses the previous one if only its servers changed.
+// In this case, it returns a function updating the servers of the previous backend, otherwise a function
+// inheriting the state of the previous backend, to call once the configuration is loaded.
+func (server *Server) loadBackend(configuration *types.Configuration, backendName string, passHostHeader bool, previous *cachedBackend, globalConfiguration GlobalConfiguration) (*cachedBackend, func(), error) {
+	if configuration.Backends[backendName] == nil {
+		return nil, nil, errors.New("Undefined backend: " + backendName)
+	}
+	if previous != nil && previous.reusable(configuration.Backends[backendName], passHostHeader, globalConfiguration.Retry) {
+		log.WithField("backend", backendName).Debug("Reusing backend from the previous configuration")
+		updateServers, err := previous.updateServers(backendName, configuration.Backends[backendName].Servers)
+		if err != nil {
+			return nil, nil, err
+		}
+		backend := *previous
+		backend.configuration = *configuration.Backends[backendName]
+		return &backend, updateServers, nil
+	}
+
+	log.WithField("backend", backendName).Debug("Creating backend")
+	backend := &cachedBackend{configuration: *configuration.Backends[backendName], passHostHeader: passHostHeader, retry: globalConfiguration.Retry}
+	fwd, _ := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(passHostHeader), forward.Rewriter(forwardedHeadersRewriter))
+	var forwarder http.Handler = fwd
+	if server.tracer != nil {
+		forwarder = middlewares.NewTracing(fwd, server.tracer, "forward "+backendName, ext.SpanKindRPCClientEnum, opentracing.Tags{"backend.name": backendName})
+	}
+	saveBackend := middlewares.NewSaveBackend(forwarder, backendName)
+	var lb http.Handler
+	var outlierDetector *middlewares.OutlierDetector
+	next := http.Handler(saveBackend)
+	if configuration.Backends[backendName].OutlierDetection != nil {
+		var err error
+		outlierDetector, err = server.buildOutlierDetector(backendName, saveBackend, configuration.Backends[backendName].OutlierDetection)
+		if err != nil {
+			return nil, nil, err
+		}
+		next = outlierDetector
+	}
+	rr, _ := roundrobin.New(next)
+	lbMethod, err := types.NewLoadBalancerMethod(configuration.Backends[backendName].LoadBalancer)
+	if err != nil {
+		configuration.Backends[backendName].LoadBalancer = &types.LoadBalancer{Method: "wrr"}
+	}
+	var balancer middlewares.ServerBalancer = rr
+	switch lbMethod {
+	case types.Drr:
+		log.Debugf("Creating load-balancer drr")
+		rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
+		lb = rebalancer
+		balancer = rebalancer
+		for serverName, server := range configuration.Backends[backendName].Servers {
+			url, err := url.Parse(server.URL)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
+			if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+				return nil, nil, err
+			}
+		}
+	case types.Wrr:
+		log.Debugf("Creating load-balancer wrr")
+		lb = rr
+		for serverName, server := range configuration.Backends[backendName].Servers {
+			url, err := url.Parse(server.URL)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
+			if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if outlierDetector != nil {
+		for _, server := range configuration.Backends[backendName].Servers {
+			url, _ := url.Parse(server.URL)
+			outlierDetector.AddServer(url, server.Weight)
+		}
+		outlierDetector.SetBalancer(balancer)
+		outlierDetector.OnStateChange(func(serverURL string, up bool) {
+			if server.metrics != nil {
+				server.metrics.SetServerUp(backendName, serverURL, up)
+			}
+			server.events.publish(event{Type: eventServerStateChanged, Provider: server.providerOfBackend(backendName), Backend: backendName, Server: serverURL, Up: &up})
+		})
+		backend.outlierDetector = outlierDetector
+	}
+	var inherit func()
+	if outlierDetector != nil && previous != nil && previous.outlierDetector != nil {
+		previousOutlierDetector := previous.outlierDetector
+		inherit = func() {
+			outlierDetector.Inherit(previousOutlierDetector)
+		}
+	}
+	backend.balancer = balancer
+	maxConns := configuration.Backends[backendName].MaxConn
+	if maxConns != nil && maxConns.Amount != 0 {
+		extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Debugf("Creating loadd-balancer connlimit")
+		lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// retry ?
+	if globalConfiguration.Retry != nil {
+		retries := len(configuration.Backends[backendName].Servers)
+		if globalConfiguration.Retry.Attempts > 0 {
+			retries = globalConfiguration.Retry.Attempts
+		}
+		maxMem := int64(2 * 1024 * 1024)
+		if globalConfiguration.Retry.MaxMem > 0 {
+			maxMem = globalConfiguration.Retry.MaxMem
+		}
+		lb, err = stream.New(lb,
+			stream.Logger(oxyLogger),
+			stream.Retry("IsNetworkError() && Attempts() < "+strconv.Itoa(retries)),
+			stream.MemRequestBodyBytes(maxMem),
+			stream.MaxRequestBodyBytes(maxMem),
+			stream.MemResponseBodyBytes(maxMem),
+			stream.MaxResponseBodyBytes(maxMem))
+		log.Debugf("Creating retries max attempts %d", retries)
+		if err != nil {
+			return nil, nil, err
+		}
+		if server.tracer != nil {
+			lb = middlewares.NewTracing(lb, server.tracer, "retry "+backendName, "", opentracing.Tags{"backend.name": backendName, "retry.attempts": retries})
+		}
+	}
+
+	var negroni = negroni.New()
+	if configuration.Backends[backendName].CircuitBreaker != nil {
+		log.Debugf("Creating circuit breaker %s", configuration.Backends[backendName].CircuitBreaker.Expression)
+		options := []cbreaker.CircuitBreakerOption{cbreaker.Logger(oxyLogger)}
+		if server.metrics != nil {
+			options = append(options, cbreaker.OnTripped(server.metrics.CircuitBreakerTripped(backendName)))
+		}
+		negroni.Use(middlewares.NewCircuitBreaker(lb, configuration.Backends[backendName].CircuitBreaker.Expression, options...))
+	} else {
+		negroni.UseHandler(lb)
+	}
+	backend.handler = negroni
+	if server.metrics != nil {
+		backend.handler = server.metrics.NewBackendMetrics(negroni, backendName)
+	}
+	return backend, inherit, nil
+}
+
+func (server *Server) buildOutlierDetector(backendName string, next http.Handler, outlierDetection *types.OutlierDetection) (*middlewares.OutlierDetector, error) {
+	consecutiveFailures := 5
+	if outlierDetection.ConsecutiveFailures > 0 {
+		consecutiveFailures = outlierDetection.ConsecutiveFailures
+	}
+	baseEjectionTime := 30 * time.Second
+	if len(outlierDetection.BaseEjectionTime) > 0 {
+		var err error
+		if baseEjectionTime, err = time.ParseDuration(outlierDetection.BaseEjectionTime); err != nil {
+			return nil, fmt.Errorf("Invalid base ejection time for backend %s: %s", backendName, err)
+		}
+	}
+	maxEjectionTime := 10 * baseEjectionTime
+	if len(outlierDetection.MaxEjectionTime) > 0 {
+		var err error
+		if maxEjectionTime, err = time.ParseDuration(outlierDetection.MaxEjectionTime); err != nil {
+			return nil, fmt.Errorf("Invalid max ejection time for backend %s: %s", backendName, err)
+		}
+	}
+	maxEjectionPercent := 10
+	if outlierDetection.MaxEjectionPercent > 0 {
+		maxEjectionPercent = outlierDetection.MaxEjectionPercent
+	}
+	log.Debugf("Creating outlier detection after %d consecutive failures, ejecting up to %d%% of the servers for %s to %s", consecutiveFailures, maxEjectionPercent, baseEjectionTime, maxEjectionTime)
+	return middlewares.NewOutlierDetector(backendName, next, consecutiveFailures, baseEjectionTime, maxEjectionTime, maxEjectionPercent), nil
+}
+
+func (server *Server) buildRateLimiter(handler http.Handler, rateLimit *types.RateLimit) (*middlewares.RateLimiter, error) {
+	extractorFunc := rateLimit.ExtractorFunc
+	if len(extractorFunc) == 0 {
+		extractorFunc = "client.ip"
+	}
+	rateNames := make([]string, 0, len(rateLimit.RateSet))
+	for rateName := range rateLimit.RateSet {
+		rateNames = append(rateNames, rateName)
+	}
+	sort.Strings(rateNames)
+	rates := []middlewares.Rate{}
+	for _, rateName := range rateNames {
+		rate := rateLimit.RateSet[rateName]
+		period, err := time.ParseDuration(rate.Period)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid period for rate %s: %s", rateName, err)
+		}
+		log.Debugf("Creating rate %s: %d requests per %s, burst %d, by %s", rateName, rate.Average, period, rate.Burst, extractorFunc)
+		rates = append(rates, middlewares.Rate{Period: period, Average: rate.Average, Burst: rate.Burst})
+	}
+	return middlewares.NewRateLimiter(handler, extractorFunc, rates)
+}
+
+func (server *Server) buildResponseCache(handler http.Handler, frontendName string, cache *types.Cache) (*middlewares.ResponseCache, error) {
+	maxSize := int64(32 * 1024 * 1024)
+	if cache.MaxSize > 0 {
+		maxSize = cache.MaxSize
+	}
+	maxEntrySize := int64(1024 * 1024)
+	if cache.MaxEntrySize > 0 {
+		maxEntrySize = cache.MaxEntrySize
+	}
+	maxDiskSize := int64(1024 * 1024 * 1024)
+	if cache.MaxDiskSize > 0 {
+		maxDiskSize = cache.MaxDiskSize
+	}
+	directory := ""
+	if len(cache.Directory) > 0 {
+		// each frontend has its own directory, so that they can share the same cache directory,
+		// named after a hash as frontend names can hold any character
+		hash := sha1.Sum([]byte(frontendName))
+		directory = filepath.Join(cache.Directory, "frontend-"+hex.EncodeToString(hash[:]))
+	}
+	responseCache, err := middlewares.NewResponseCache(handler, maxSize, maxEntrySize, directory, maxDiskSize)
+	if err != nil {
+		return nil, err
+	}
+	if server.metrics != nil {
+		responseCache.OnRequest(server.metrics.CacheRequested(frontendName))
+	}
+	return responseCache, nil
+}
+
+// newAccessLogger returns the access logger of the configuration.
+func newAccessLogger(globalConfiguration GlobalConfiguration) (*middlewares.Logger, error) {
+	accessLog := globalConfiguration.AccessLog
+	if accessLog == nil {
+		accessLog = &AccessLog{}
+	}
+	return middlewares.NewLogger(globalConfiguration.AccessLogsFile, middlewares.AccessLogOptions{
+		Format:          accessLog.Format,
+		Fields:          accessLog.Fields,
+		RequestHeaders:  accessLog.RequestHeaders,
+		ResponseHeaders: accessLog.ResponseHeaders,
+		RedactedHeaders: accessLog.RedactedHeaders,
+		KeepQueryString: accessLog.KeepQueryString,
+		StatusCodes:     accessLog.StatusCodes,
+		MinDuration:     accessLog.MinDuration,
+		Frontends:       accessLog.Frontends,
+		Sampling:        accessLog.Sampling,
+		BufferSize:      accessLog.BufferSize,
+		FlushInterval:   accessLog.FlushInterval,
+	})
+}
+
+func (server *Server) buildForwardedHeaders(forwardedHeaders *ForwardedHeaders) (*middlewares.ForwardedHeaders, error) {
+	if forwardedHeaders == nil {
+		return middlewares.NewForwardedHeadersPassthrough(), nil
+	}
+	return middlewares.NewForwardedHeaders(forwardedHeaders.Insecure, forwardedHeaders.TrustedIPs)
+}
+
+func (server *Server) buildMaintenance(handler http.Handler, maintenance *types.Maintenance) (*middlewares.Maintenance, error) {
+	if maintenance == nil {
+		maintenance = &types.Maintenance{}
+	}
+	var page []byte
+	if len(maintenance.Page) > 0 {
+		var err error
+		page, err = ioutil.ReadFile(maintenance.Page)
+		if err != nil {
+			return nil, err
+		}
+	}
+	retryAfter := maintenance.RetryAfter
+	if len(retryAfter) == 0 {
+		retryAfter = "300"
+	}
+	return middlewares.NewMaintenance(handler, maintenance.Enabled, page, retryAfter, maintenance.SourceRange)
+}
+
+func (server *Server) buildAuthenticator(handler http.Handler, auth *types.Auth) (*middlewares.Authenticator, error) {
+	realm := auth.Realm
+	if len(realm) == 0 {
+		realm = "traefik"
+	}
+	headerField := auth.HeaderField
+	if len(headerField) == 0 {
+		headerField = "X-Forwarded-User"
+	}
+	switch {
+	case auth.Basic != nil && auth.Digest != nil:
+		return nil, errors.New("Basic and digest authentication cannot be used together")
+	case auth.Basic != nil:
+		log.Debugf("Creating basic authentication for realm %s with %d users and users file %q", realm, len(auth.Basic.Users), auth.Basic.UsersFile)
+		return middlewares.NewBasicAuth(handler, realm, auth.Basic.Users, auth.Basic.UsersFile, headerField)
+	case auth.Digest != nil:
+		log.Debugf("Creating digest authentication for realm %s with %d users and users file %q", realm, len(auth.Digest.Users), auth.Digest.UsersFile)
+		return middlewares.NewDigestAuth(handler, realm, auth.Digest.Users, auth.Digest.UsersFile, headerField)
+	}
+	return nil, errors.New("No basic or digest authentication defined")
+}
+
+func (server *Server) buildForwardAuth(forwardAuth *types.ForwardAuth) (*middlewares.ForwardAuth, error) {
+	if len(forwardAuth.Address) == 0 {
+		return nil, errors.New("No authentication service address defined")
+	}
+	var tlsConfig *tls.Config
+	if forwardAuth.TLS != nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: forwardAuth.TLS.InsecureSkipVerify}
+		if len(forwardAuth.TLS.CA) > 0 {
+			ca, err := ioutil.ReadFile(forwardAuth.TLS.CA)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, errors.New("No certificate found in CA " + forwardAuth.TLS.CA)
+			}
+		}
+		if len(forwardAuth.TLS.Cert) > 0 || len(forwardAuth.TLS.Key) > 0 {
+			cert, err := tls.LoadX509KeyPair(forwardAuth.TLS.Cert, forwardAuth.TLS.Key)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return middlewares.NewForwardAuth(forwardAuth.Address, tlsConfig, forwardAuth.TrustForwardHeader, forwardAuth.AuthResponseHeaders)
+}
+
 func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http.Handler) {
 	// strip prefix
 	if len(serverRoute.stripPrefixes) > 0 {
@@ -558,6 +1186,9 @@ func (server *Server) loadEntryPointConfig(entryPointName string, entryPoint *En
 func (server *Server) buildDefaultHTTPRouter() *mux.Router {
 	router := mux.NewRouter()
 	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	if server.notFoundPage != nil {
+		router.NotFoundHandler = server.notFoundPage
+	}
 	router.StrictSlash(true)
 	return router
 }
@@ -588,3 +1219,12 @@ func sortedFrontendNamesForConfig(configuration *types.Configuration) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+func sortedErrorPageNames(frontend *types.Frontend) []string {
+	keys := []string{}
+	for key := range frontend.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
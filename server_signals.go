@@ -0,0 +1,33 @@
+// +build !windows
+
+package main
+
+import (
+	"os/signal"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func (server *Server) configureSignals() {
+	signal.Notify(server.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
+}
+
+func (server *Server) listenSignals() {
+	for {
+		sig, ok := <-server.signals
+		if !ok {
+			return
+		}
+		switch sig {
+		case syscall.SIGUSR1:
+			log.Infof("Closing and re-opening log files for rotation: %+v", sig)
+			server.reopenLogs()
+		default:
+			log.Infof("I have to go... %+v", sig)
+			log.Info("Stopping server")
+			server.Stop()
+			return
+		}
+	}
+}
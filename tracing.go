@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+	zipkintransport "github.com/uber/jaeger-client-go/transport/zipkin"
+	jaegerzipkin "github.com/uber/jaeger-client-go/zipkin"
+)
+
+// newTracer creates the tracer reporting the spans to the configured backend, and the closer
+// flushing the spans which are not reported yet.
+func newTracer(tracing *Tracing) (opentracing.Tracer, io.Closer, error) {
+	serviceName := tracing.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = "traefik"
+	}
+	samplingRate := tracing.SamplingRate
+	if samplingRate <= 0 || samplingRate > 1 {
+		samplingRate = 1
+	}
+
+	configuration := jaegercfg.Configuration{
+		Sampler: &jaegercfg.SamplerConfig{
+			Type:  jaeger.SamplerTypeProbabilistic,
+			Param: samplingRate,
+		},
+	}
+	options := []jaegercfg.Option{jaegercfg.Logger(jaegerLogger{})}
+
+	switch tracing.Backend {
+	case "", "jaeger":
+		jaegerTracing := tracing.Jaeger
+		if jaegerTracing == nil {
+			jaegerTracing = &JaegerTracing{}
+		}
+		localAgentHostPort := jaegerTracing.LocalAgentHostPort
+		if len(localAgentHostPort) == 0 {
+			localAgentHostPort = "127.0.0.1:6831"
+		}
+		configuration.Reporter = &jaegercfg.ReporterConfig{
+			LocalAgentHostPort: localAgentHostPort,
+		}
+		switch jaegerTracing.Propagation {
+		case "", "jaeger":
+		case "b3":
+			options = append(options, b3Propagation()...)
+		default:
+			return nil, nil, fmt.Errorf("Unknown Jaeger propagation %s", jaegerTracing.Propagation)
+		}
+		log.Infof("Reporting spans of service %s to Jaeger agent %s", serviceName, localAgentHostPort)
+		return configuration.New(serviceName, options...)
+	case "zipkin":
+		if tracing.Zipkin == nil || len(tracing.Zipkin.HTTPEndpoint) == 0 {
+			return nil, nil, errors.New("Missing Zipkin HTTP endpoint")
+		}
+		// the Jaeger tracer reports to Zipkin in its format, with the client and server sides of
+		// a call in the same span, as Zipkin tracers do
+		transport, err := zipkintransport.NewHTTPTransport(tracing.Zipkin.HTTPEndpoint, zipkintransport.HTTPLogger(jaegerLogger{}))
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(append(options, b3Propagation()...),
+			jaegercfg.Reporter(jaeger.NewRemoteReporter(transport, jaeger.ReporterOptions.Logger(jaegerLogger{}))),
+			jaegercfg.ZipkinSharedRPCSpan(true))
+		log.Infof("Reporting spans of service %s to Zipkin at %s", serviceName, tracing.Zipkin.HTTPEndpoint)
+		return configuration.New(serviceName, options...)
+	default:
+		return nil, nil, fmt.Errorf("Unknown tracing backend %s", tracing.Backend)
+	}
+}
+
+// b3Propagation propagates the span context in the X-B3-* headers of Zipkin.
+func b3Propagation() []jaegercfg.Option {
+	propagator := jaegerzipkin.NewZipkinB3HTTPHeaderPropagator()
+	return []jaegercfg.Option{
+		jaegercfg.Injector(opentracing.HTTPHeaders, propagator),
+		jaegercfg.Extractor(opentracing.HTTPHeaders, propagator),
+	}
+}
+
+// jaegerLogger logs the errors of the Jaeger tracer with logrus.
+type jaegerLogger struct{}
+
+func (jaegerLogger) Error(msg string) {
+	log.Errorf("Jaeger tracer: %s", msg)
+}
+
+func (jaegerLogger) Infof(msg string, args ...interface{}) {
+	log.Debugf("Jaeger tracer: "+msg, args...)
+}
@@ -7,10 +7,11 @@ import (
 
 // Backend holds backend configuration.
 type Backend struct {
-	Servers        map[string]Server `json:"servers,omitempty"`
-	CircuitBreaker *CircuitBreaker   `json:"circuitBreaker,omitempty"`
-	LoadBalancer   *LoadBalancer     `json:"loadBalancer,omitempty"`
-	MaxConn        *MaxConn          `json:"maxConn,omitempty"`
+	Servers          map[string]Server `json:"servers,omitempty"`
+	CircuitBreaker   *CircuitBreaker   `json:"circuitBreaker,omitempty"`
+	LoadBalancer     *LoadBalancer     `json:"loadBalancer,omitempty"`
+	MaxConn          *MaxConn          `json:"maxConn,omitempty"`
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
 }
 
 // MaxConn holds maximum connection configuration
@@ -29,6 +30,14 @@ type CircuitBreaker struct {
 	Expression string `json:"expression,omitempty"`
 }
 
+// OutlierDetection holds passive health checking configuration, ejecting servers failing consecutively.
+type OutlierDetection struct {
+	ConsecutiveFailures int    `json:"consecutiveFailures,omitempty"`
+	BaseEjectionTime    string `json:"baseEjectionTime,omitempty"`
+	MaxEjectionTime     string `json:"maxEjectionTime,omitempty"`
+	MaxEjectionPercent  int    `json:"maxEjectionPercent,omitempty"`
+}
+
 // Server holds server configuration.
 type Server struct {
 	URL    string `json:"url,omitempty"`
@@ -46,10 +55,148 @@ type Route struct {
 
 // Frontend holds frontend configuration.
 type Frontend struct {
-	EntryPoints    []string         `json:"entryPoints,omitempty"`
-	Backend        string           `json:"backend,omitempty"`
-	Routes         map[string]Route `json:"routes,omitempty"`
-	PassHostHeader bool             `json:"passHostHeader,omitempty"`
+	EntryPoints    []string              `json:"entryPoints,omitempty"`
+	Backend        string                `json:"backend,omitempty"`
+	Routes         map[string]Route      `json:"routes,omitempty"`
+	PassHostHeader bool                  `json:"passHostHeader,omitempty"`
+	Mirror         *Mirror               `json:"mirror,omitempty"`
+	Backends       []WeightedBackend     `json:"backends,omitempty"`
+	RateLimit      *RateLimit            `json:"rateLimit,omitempty"`
+	Errors         map[string]*ErrorPage `json:"errors,omitempty"`
+	Auth           *Auth                 `json:"auth,omitempty"`
+	ForwardAuth    *ForwardAuth          `json:"forwardAuth,omitempty"`
+	Headers        *Headers              `json:"headers,omitempty"`
+	Security       *Security             `json:"security,omitempty"`
+	CORS           *CORS                 `json:"cors,omitempty"`
+	Cache          *Cache                `json:"cache,omitempty"`
+	Maintenance    *Maintenance          `json:"maintenance,omitempty"`
+}
+
+// WeightedBackend holds the weight of a backend when a frontend splits its traffic between several backends.
+type WeightedBackend struct {
+	Name   string `json:"name,omitempty"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// Mirror holds traffic mirroring configuration.
+type Mirror struct {
+	Backend     string `json:"backend,omitempty"`
+	Percent     int    `json:"percent,omitempty"`
+	MaxBodySize int64  `json:"maxBodySize,omitempty"`
+	MaxInFlight int    `json:"maxInFlight,omitempty"`
+}
+
+// RateLimit holds rate limiting configuration for a frontend.
+type RateLimit struct {
+	RateSet       map[string]*Rate `json:"rateSet,omitempty"`
+	ExtractorFunc string           `json:"extractorFunc,omitempty"`
+}
+
+// Rate holds a rate limiting tier: an average number of requests per period, with bursts up to burst requests.
+type Rate struct {
+	Period  string `json:"period,omitempty"`
+	Average int64  `json:"average,omitempty"`
+	Burst   int64  `json:"burst,omitempty"`
+}
+
+// ErrorPage holds a custom error page: the responses whose status matches one of the status codes
+// or ranges of status codes (like "404" or "500-599") are replaced with the page served by backend at query.
+type ErrorPage struct {
+	Status  []string `json:"status,omitempty"`
+	Backend string   `json:"backend,omitempty"`
+	Query   string   `json:"query,omitempty"`
+}
+
+// Auth holds authentication configuration: the requests must be authenticated with HTTP basic or digest
+// authentication, and the authenticated user is forwarded to the backends in headerField (default: X-Forwarded-User).
+type Auth struct {
+	Basic       *Basic  `json:"basic,omitempty"`
+	Digest      *Digest `json:"digest,omitempty"`
+	Realm       string  `json:"realm,omitempty"`
+	HeaderField string  `json:"headerField,omitempty"`
+}
+
+// Basic holds HTTP basic authentication users, in htpasswd format (user:hash), inline or from a file.
+type Basic struct {
+	Users     []string `json:"users,omitempty"`
+	UsersFile string   `json:"usersFile,omitempty"`
+}
+
+// Digest holds HTTP digest authentication users, in htdigest format (user:realm:hash), inline or from a file.
+type Digest struct {
+	Users     []string `json:"users,omitempty"`
+	UsersFile string   `json:"usersFile,omitempty"`
+}
+
+// ForwardAuth holds forward authentication configuration: each request is first sent to the authentication
+// service at address, and only the requests it answers with a 2xx status are forwarded.
+type ForwardAuth struct {
+	Address             string     `json:"address,omitempty"`
+	TLS                 *ClientTLS `json:"tls,omitempty"`
+	TrustForwardHeader  bool       `json:"trustForwardHeader,omitempty"`
+	AuthResponseHeaders []string   `json:"authResponseHeaders,omitempty"`
+}
+
+// ClientTLS holds the TLS configuration used to connect to a service.
+type ClientTLS struct {
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// Headers holds the headers to set and remove on the requests of a frontend and on their responses.
+// The values of the custom headers can hold the {client.ip}, {frontend.name}, {request.host} and {request.id} placeholders.
+type Headers struct {
+	CustomRequestHeaders  map[string]string `json:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders map[string]string `json:"customResponseHeaders,omitempty"`
+	RemoveRequestHeaders  []string          `json:"removeRequestHeaders,omitempty"`
+	RemoveResponseHeaders []string          `json:"removeResponseHeaders,omitempty"`
+}
+
+// Security holds the security headers of a frontend and its checks: the requests to hosts other than
+// the allowed hosts are rejected, and plain HTTP requests are redirected to HTTPS if sslRedirect is set.
+type Security struct {
+	AllowedHosts          []string `json:"allowedHosts,omitempty"`
+	SSLRedirect           bool     `json:"sslRedirect,omitempty"`
+	SSLHost               string   `json:"sslHost,omitempty"`
+	STSSeconds            int64    `json:"stsSeconds,omitempty"`
+	STSIncludeSubdomains  bool     `json:"stsIncludeSubdomains,omitempty"`
+	STSPreload            bool     `json:"stsPreload,omitempty"`
+	FrameOptions          string   `json:"frameOptions,omitempty"`
+	ContentTypeNosniff    bool     `json:"contentTypeNosniff,omitempty"`
+	ContentSecurityPolicy string   `json:"contentSecurityPolicy,omitempty"`
+	ReferrerPolicy        string   `json:"referrerPolicy,omitempty"`
+}
+
+// CORS holds the Cross-Origin Resource Sharing policy of a frontend. Its preflight requests are answered
+// without being forwarded to the backend.
+type CORS struct {
+	AllowedOrigins   []string `json:"allowedOrigins,omitempty"`
+	AllowedMethods   []string `json:"allowedMethods,omitempty"`
+	AllowedHeaders   []string `json:"allowedHeaders,omitempty"`
+	ExposedHeaders   []string `json:"exposedHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	MaxAge           int      `json:"maxAge,omitempty"`
+}
+
+// Cache holds the response cache of a frontend: the responses are kept in memory up to maxSize bytes,
+// then moved to directory, if set, up to maxDiskSize bytes. Responses bigger than maxEntrySize are not cached.
+type Cache struct {
+	MaxSize      int64  `json:"maxSize,omitempty"`
+	MaxEntrySize int64  `json:"maxEntrySize,omitempty"`
+	Directory    string `json:"directory,omitempty"`
+	MaxDiskSize  int64  `json:"maxDiskSize,omitempty"`
+}
+
+// Maintenance holds the maintenance mode of a frontend: while enabled, the requests are answered
+// with a 503 status, the content of the page file if set, and a Retry-After header (seconds or HTTP date),
+// except the requests coming from the source ranges (IPs or CIDRs), which reach the backends.
+type Maintenance struct {
+	Enabled     bool     `json:"enabled,omitempty"`
+	Page        string   `json:"page,omitempty"`
+	RetryAfter  string   `json:"retryAfter,omitempty"`
+	SourceRange []string `json:"sourceRange,omitempty"`
 }
 
 // LoadBalancerMethod holds the method of load balancing to use.
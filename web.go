@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/containous/traefik/autogen"
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/elazarl/go-bindata-assetfs"
@@ -25,6 +29,12 @@ type WebProvider struct {
 	CertFile, KeyFile string
 	ReadOnly          bool
 	server            *Server
+	configurationChan chan<- types.ConfigMessage
+	// held from the ETag check of an update until it is sent
+	updateMutex sync.Mutex
+	// configurations sent, waiting to be applied
+	configurationMutex sync.Mutex
+	pending            []*pendingWebConfiguration
 }
 
 var (
@@ -36,21 +46,26 @@ var (
 // Provide allows the provider to provide configurations to traefik
 // using the given configuration channel.
 func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
+	provider.configurationChan = configurationChan
 	systemRouter := mux.NewRouter()
 
 	// health route
 	systemRouter.Methods("GET").Path("/health").HandlerFunc(provider.getHealthHandler)
+	systemRouter.Methods("GET").Path("/health/mirrors").HandlerFunc(provider.getMirrorsHealthHandler)
+	systemRouter.Methods("GET").Path("/health/outliers").HandlerFunc(provider.getOutliersHealthHandler)
+	systemRouter.Methods("GET").Path("/health/caches").HandlerFunc(provider.getCachesHealthHandler)
+	systemRouter.Methods("GET").Path("/health/accesslog").HandlerFunc(provider.getAccessLogHealthHandler)
+
+	// metrics route
+	if provider.server.metricsHandler != nil {
+		systemRouter.Methods("GET").Path("/metrics").Handler(provider.server.metricsHandler)
+	}
 
 	// API routes
 	systemRouter.Methods("GET").Path("/api").HandlerFunc(provider.getConfigHandler)
 	systemRouter.Methods("GET").Path("/api/providers").HandlerFunc(provider.getConfigHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}").HandlerFunc(provider.getProviderHandler)
 	systemRouter.Methods("PUT").Path("/api/providers/{provider}").HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
-		if provider.ReadOnly {
-			response.WriteHeader(http.StatusForbidden)
-			fmt.Fprintf(response, "REST API is in read-only mode")
-			return
-		}
 		vars := mux.Vars(request)
 		if vars["provider"] != "web" {
 			response.WriteHeader(http.StatusBadRequest)
@@ -58,17 +73,23 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 			return
 		}
 
-		configuration := new(types.Configuration)
-		body, _ := ioutil.ReadAll(request.Body)
-		err := json.Unmarshal(body, configuration)
-		if err == nil {
-			configurationChan <- types.ConfigMessage{"web", configuration}
-			provider.getConfigHandler(response, request)
-		} else {
-			log.Errorf("Error parsing configuration %+v", err)
-			http.Error(response, fmt.Sprintf("%+v", err), http.StatusBadRequest)
-		}
+		provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+			newConfiguration := new(types.Configuration)
+			if status, err := readBody(request, newConfiguration); err != nil {
+				return nil, status, err
+			}
+			*configuration = *newConfiguration
+			return func() interface{} {
+				return provider.server.currentConfigurations.Get().(configs)
+			}, http.StatusOK, nil
+		})
 	})
+	systemRouter.Methods("PUT").Path("/api/providers/web/frontends/{frontend}").HandlerFunc(provider.putWebFrontendHandler)
+	systemRouter.Methods("DELETE").Path("/api/providers/web/frontends/{frontend}").HandlerFunc(provider.deleteWebFrontendHandler)
+	systemRouter.Methods("PUT").Path("/api/providers/web/backends/{backend}").HandlerFunc(provider.putWebBackendHandler)
+	systemRouter.Methods("DELETE").Path("/api/providers/web/backends/{backend}").HandlerFunc(provider.deleteWebBackendHandler)
+	systemRouter.Methods("PUT").Path("/api/providers/web/backends/{backend}/servers/{server}").HandlerFunc(provider.putWebServerHandler)
+	systemRouter.Methods("DELETE").Path("/api/providers/web/backends/{backend}/servers/{server}").HandlerFunc(provider.deleteWebServerHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends").HandlerFunc(provider.getBackendsHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}").HandlerFunc(provider.getBackendHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}/servers").HandlerFunc(provider.getServersHandler)
@@ -77,6 +98,18 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}").HandlerFunc(provider.getFrontendHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}/routes").HandlerFunc(provider.getRoutesHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}/routes/{route}").HandlerFunc(provider.getRouteHandler)
+	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}/weights").HandlerFunc(provider.getWeightsHandler)
+	systemRouter.Methods("PUT").Path("/api/providers/{provider}/frontends/{frontend}/weights").HandlerFunc(provider.putWeightsHandler)
+	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}/maintenance").HandlerFunc(provider.getMaintenanceHandler)
+	systemRouter.Methods("PUT").Path("/api/providers/{provider}/frontends/{frontend}/maintenance").HandlerFunc(provider.putMaintenanceHandler)
+	systemRouter.Methods("DELETE").Path("/api/cache").HandlerFunc(provider.deleteCacheHandler)
+	systemRouter.Methods("GET").Path("/api/events").HandlerFunc(provider.getEventsHandler)
+	systemRouter.Methods("GET").Path("/api/history").HandlerFunc(provider.getHistoryHandler)
+	systemRouter.Methods("GET").Path("/api/history/{provider}").HandlerFunc(provider.getProviderHistoryHandler)
+	systemRouter.Methods("GET").Path("/api/history/{provider}/{version:[0-9]+}").HandlerFunc(provider.getHistoryVersionHandler)
+	systemRouter.Methods("POST").Path("/api/history/{provider}/{version:[0-9]+}/rollback").HandlerFunc(provider.postRollbackHandler)
+	systemRouter.Methods("GET").Path("/api/logs/level").HandlerFunc(provider.getLogLevelHandler)
+	systemRouter.Methods("PUT").Path("/api/logs/level").HandlerFunc(provider.putLogLevelHandler)
 
 	// Expose dashboard
 	systemRouter.Methods("GET").Path("/").HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
@@ -104,6 +137,193 @@ func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, requ
 	templatesRenderer.JSON(response, http.StatusOK, metrics.Data())
 }
 
+func (provider *WebProvider) getMirrorsHealthHandler(response http.ResponseWriter, request *http.Request) {
+	frontends := provider.server.frontends.Get().(map[string]*cachedFrontend)
+	mirrorsStats := make(map[string]middlewares.MirrorStats)
+	for frontendName, frontend := range frontends {
+		if frontend.mirror != nil {
+			mirrorsStats[frontendName] = frontend.mirror.Stats()
+		}
+	}
+	templatesRenderer.JSON(response, http.StatusOK, mirrorsStats)
+}
+
+func (provider *WebProvider) getOutliersHealthHandler(response http.ResponseWriter, request *http.Request) {
+	backends := provider.server.backends.Get().(map[string]*cachedBackend)
+	ejectedServers := make(map[string][]middlewares.EjectedServer)
+	for backendName, backend := range backends {
+		if backend.outlierDetector != nil {
+			ejectedServers[backendName] = backend.outlierDetector.Ejected()
+		}
+	}
+	templatesRenderer.JSON(response, http.StatusOK, ejectedServers)
+}
+
+func (provider *WebProvider) getCachesHealthHandler(response http.ResponseWriter, request *http.Request) {
+	frontends := provider.server.frontends.Get().(map[string]*cachedFrontend)
+	cachesStats := make(map[string]middlewares.CacheStats)
+	for frontendName, frontend := range frontends {
+		if frontend.responseCache != nil {
+			cachesStats[frontendName] = frontend.responseCache.Stats()
+		}
+	}
+	templatesRenderer.JSON(response, http.StatusOK, cachesStats)
+}
+
+func (provider *WebProvider) getAccessLogHealthHandler(response http.ResponseWriter, request *http.Request) {
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.loggerMiddleware.Stats())
+}
+
+// deleteCacheHandler purges the responses cached for the url query parameter, or for all the URLs
+// starting with the prefix query parameter, in the caches of all the frontends.
+func (provider *WebProvider) deleteCacheHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(response, "REST API is in read-only mode")
+		return
+	}
+	url, prefix := request.URL.Query().Get("url"), false
+	if len(url) == 0 {
+		url, prefix = request.URL.Query().Get("prefix"), true
+	}
+	if len(url) == 0 {
+		http.Error(response, "Missing url or prefix parameter", http.StatusBadRequest)
+		return
+	}
+	frontends := provider.server.frontends.Get().(map[string]*cachedFrontend)
+	purged := 0
+	for _, frontend := range frontends {
+		if frontend.responseCache != nil {
+			purged += frontend.responseCache.Purge(url, prefix)
+		}
+	}
+	log.Infof("Purged %d cached responses for %s (prefix: %t)", purged, url, prefix)
+	templatesRenderer.JSON(response, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// getEventsHandler streams the events of the server as Server-Sent Events, until the client disconnects.
+func (provider *WebProvider) getEventsHandler(response http.ResponseWriter, request *http.Request) {
+	flusher, ok := response.(http.Flusher)
+	if !ok {
+		http.Error(response, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var closed <-chan bool
+	if closeNotifier, ok := response.(http.CloseNotifier); ok {
+		closed = closeNotifier.CloseNotify()
+	}
+	events := provider.server.events.subscribe()
+	defer provider.server.events.unsubscribe(events)
+
+	response.Header().Set("Content-Type", "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-closed:
+			return
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				log.Errorf("Error marshaling %s event: %s", e.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(response, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (provider *WebProvider) getHistoryHandler(response http.ResponseWriter, request *http.Request) {
+	history := make(map[string][]configurationVersion)
+	for _, providerName := range provider.server.history.providers() {
+		history[providerName] = provider.server.history.summaries(providerName)
+	}
+	templatesRenderer.JSON(response, http.StatusOK, history)
+}
+
+func (provider *WebProvider) getProviderHistoryHandler(response http.ResponseWriter, request *http.Request) {
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.history.summaries(mux.Vars(request)["provider"]))
+}
+
+func (provider *WebProvider) getHistoryVersion(request *http.Request) (*configurationVersion, bool) {
+	vars := mux.Vars(request)
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		return nil, false
+	}
+	return provider.server.history.get(vars["provider"], version)
+}
+
+func (provider *WebProvider) getHistoryVersionHandler(response http.ResponseWriter, request *http.Request) {
+	version, ok := provider.getHistoryVersion(request)
+	if !ok {
+		http.NotFound(response, request)
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, version)
+}
+
+// postRollbackHandler applies again a version of the configuration of the web provider.
+func (provider *WebProvider) postRollbackHandler(response http.ResponseWriter, request *http.Request) {
+	if mux.Vars(request)["provider"] != "web" {
+		response.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(response, "Only 'web' provider can be rolled back through the REST API")
+		return
+	}
+	version, ok := provider.getHistoryVersion(request)
+	if !ok {
+		http.NotFound(response, request)
+		return
+	}
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		log.Infof("Rolling back the web provider configuration to version %d of %s", version.Version, version.Time)
+		// the version kept in the history must not share anything with the configuration sent
+		versionConfiguration, err := copyConfiguration(version.Configuration)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		*configuration = *versionConfiguration
+		return version, http.StatusOK, nil
+	})
+}
+
+// logLevelState is the runtime level of the Traefik logs exposed by the REST API.
+type logLevelState struct {
+	Level string `json:"level"`
+}
+
+func (provider *WebProvider) getLogLevelHandler(response http.ResponseWriter, request *http.Request) {
+	templatesRenderer.JSON(response, http.StatusOK, logLevelState{Level: log.GetLevel().String()})
+}
+
+func (provider *WebProvider) putLogLevelHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(response, "REST API is in read-only mode")
+		return
+	}
+	state := logLevelState{}
+	body, _ := ioutil.ReadAll(request.Body)
+	if err := json.Unmarshal(body, &state); err != nil {
+		log.Errorf("Error parsing log level %+v", err)
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	level, err := log.ParseLevel(strings.ToLower(state.Level))
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.SetLevel(level)
+	log.Warnf("Log level set to %s", level)
+	templatesRenderer.JSON(response, http.StatusOK, logLevelState{Level: log.GetLevel().String()})
+}
+
 func (provider *WebProvider) getConfigHandler(response http.ResponseWriter, request *http.Request) {
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
 	templatesRenderer.JSON(response, http.StatusOK, currentConfigurations)
@@ -112,6 +332,7 @@ func (provider *WebProvider) getConfigHandler(response http.ResponseWriter, requ
 func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
+	provider.setWebETag(response, providerID)
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		templatesRenderer.JSON(response, http.StatusOK, provider)
@@ -134,6 +355,7 @@ func (provider *WebProvider) getBackendsHandler(response http.ResponseWriter, re
 func (provider *WebProvider) getBackendHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
+	provider.setWebETag(response, providerID)
 	backendID := vars["backend"]
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
 	if provider, ok := currentConfigurations[providerID]; ok {
@@ -162,6 +384,7 @@ func (provider *WebProvider) getServersHandler(response http.ResponseWriter, req
 func (provider *WebProvider) getServerHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
+	provider.setWebETag(response, providerID)
 	backendID := vars["backend"]
 	serverID := vars["server"]
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
@@ -190,6 +413,7 @@ func (provider *WebProvider) getFrontendsHandler(response http.ResponseWriter, r
 func (provider *WebProvider) getFrontendHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
+	provider.setWebETag(response, providerID)
 	frontendID := vars["frontend"]
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
 	if provider, ok := currentConfigurations[providerID]; ok {
@@ -231,3 +455,99 @@ func (provider *WebProvider) getRouteHandler(response http.ResponseWriter, reque
 	}
 	http.NotFound(response, request)
 }
+
+// getCachedFrontend returns the frontend of the request variables, if it is currently served.
+func (provider *WebProvider) getCachedFrontend(request *http.Request) (*cachedFrontend, bool) {
+	vars := mux.Vars(request)
+	providerID := vars["provider"]
+	frontendID := vars["frontend"]
+	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	if provider, ok := currentConfigurations[providerID]; ok {
+		if _, ok := provider.Frontends[frontendID]; !ok {
+			return nil, false
+		}
+	} else {
+		return nil, false
+	}
+	frontend, ok := provider.server.frontends.Get().(map[string]*cachedFrontend)[frontendID]
+	return frontend, ok
+}
+
+func (provider *WebProvider) getSplitter(request *http.Request) (*middlewares.WeightedSplitter, bool) {
+	frontend, ok := provider.getCachedFrontend(request)
+	if !ok || frontend.splitter == nil {
+		return nil, false
+	}
+	return frontend.splitter, true
+}
+
+func (provider *WebProvider) getWeightsHandler(response http.ResponseWriter, request *http.Request) {
+	if splitter, ok := provider.getSplitter(request); ok {
+		templatesRenderer.JSON(response, http.StatusOK, splitter.Weights())
+	} else {
+		http.NotFound(response, request)
+	}
+}
+
+func (provider *WebProvider) putWeightsHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(response, "REST API is in read-only mode")
+		return
+	}
+	splitter, ok := provider.getSplitter(request)
+	if !ok {
+		http.NotFound(response, request)
+		return
+	}
+	weights := map[string]int{}
+	body, _ := ioutil.ReadAll(request.Body)
+	if err := json.Unmarshal(body, &weights); err != nil {
+		log.Errorf("Error parsing weights %+v", err)
+		http.Error(response, fmt.Sprintf("%+v", err), http.StatusBadRequest)
+		return
+	}
+	if err := splitter.SetWeights(weights); err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Infof("Weights of frontend %s updated to %v", mux.Vars(request)["frontend"], weights)
+	templatesRenderer.JSON(response, http.StatusOK, splitter.Weights())
+}
+
+// maintenanceState is the runtime maintenance state of a frontend exposed by the REST API.
+type maintenanceState struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (provider *WebProvider) getMaintenanceHandler(response http.ResponseWriter, request *http.Request) {
+	frontend, ok := provider.getCachedFrontend(request)
+	if !ok || frontend.maintenance == nil {
+		http.NotFound(response, request)
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, maintenanceState{Enabled: frontend.maintenance.Enabled()})
+}
+
+func (provider *WebProvider) putMaintenanceHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(response, "REST API is in read-only mode")
+		return
+	}
+	frontend, ok := provider.getCachedFrontend(request)
+	if !ok || frontend.maintenance == nil {
+		http.NotFound(response, request)
+		return
+	}
+	state := maintenanceState{}
+	body, _ := ioutil.ReadAll(request.Body)
+	if err := json.Unmarshal(body, &state); err != nil {
+		log.Errorf("Error parsing maintenance state %+v", err)
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	frontend.maintenance.SetEnabled(state.Enabled)
+	log.Infof("Maintenance of frontend %s set to %t", mux.Vars(request)["frontend"], state.Enabled)
+	templatesRenderer.JSON(response, http.StatusOK, maintenanceState{Enabled: frontend.maintenance.Enabled()})
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/types"
+	"github.com/gorilla/mux"
+)
+
+// webConfigurationTimeout is how long an update of the web provider waits for its configuration
+// to be applied, after the providersThrottleDuration.
+const webConfigurationTimeout = 10 * time.Second
+
+var errWebConfigurationReplaced = errors.New("replaced by a later update, which was rejected")
+
+// webConfigurationUpdate changes the configuration of the web provider, returning what to respond,
+// or the status and error to respond with if it cannot. If what to respond is a func() interface{},
+// it is called once the configuration is applied.
+type webConfigurationUpdate func(configuration *types.Configuration) (interface{}, int, error)
+
+// pendingWebConfiguration is a configuration sent by the web provider, until it is applied or rejected.
+type pendingWebConfiguration struct {
+	configuration *types.Configuration
+	// copy of configuration, which loadConfig may change, the next updates apply to
+	sent   *types.Configuration
+	loaded chan error
+}
+
+// webConfiguration returns a copy of the configuration of the web provider currently applied, and its ETag.
+func (provider *WebProvider) webConfiguration() (*types.Configuration, string, error) {
+	configuration := provider.server.currentConfigurations.Get().(configs)["web"]
+	if configuration == nil {
+		configuration = &types.Configuration{}
+	}
+	return copyWebConfiguration(configuration)
+}
+
+// nextWebConfiguration returns a copy of the configuration of the web provider the next update
+// applies to, the last one sent if it is not applied yet, and its ETag.
+func (provider *WebProvider) nextWebConfiguration() (*types.Configuration, string, error) {
+	provider.configurationMutex.Lock()
+	var sent *types.Configuration
+	if len(provider.pending) > 0 {
+		sent = provider.pending[len(provider.pending)-1].sent
+	}
+	provider.configurationMutex.Unlock()
+	if sent == nil {
+		return provider.webConfiguration()
+	}
+	return copyWebConfiguration(sent)
+}
+
+// copyWebConfiguration returns a copy of a configuration of the web provider, and its ETag.
+func copyWebConfiguration(configuration *types.Configuration) (*types.Configuration, string, error) {
+	etag, err := webETag(configuration)
+	if err != nil {
+		return nil, "", err
+	}
+	configurationCopy, err := copyConfiguration(configuration)
+	if err != nil {
+		return nil, "", err
+	}
+	return configurationCopy, etag, nil
+}
+
+// webETag returns the ETag of a configuration of the web provider, the hash of its JSON as served
+// by GET /api/providers/web.
+func webETag(configuration *types.Configuration) (string, error) {
+	data, err := json.Marshal(configuration)
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.Sum(data)
+	return `"` + hex.EncodeToString(hash[:]) + `"`, nil
+}
+
+// copyConfiguration returns a deep copy of configuration, which loadConfig may change.
+func copyConfiguration(configuration *types.Configuration) (*types.Configuration, error) {
+	data, err := json.Marshal(configuration)
+	if err != nil {
+		return nil, err
+	}
+	configurationCopy := &types.Configuration{}
+	if err := json.Unmarshal(data, configurationCopy); err != nil {
+		return nil, err
+	}
+	if configurationCopy.Frontends == nil {
+		configurationCopy.Frontends = map[string]*types.Frontend{}
+	}
+	if configurationCopy.Backends == nil {
+		configurationCopy.Backends = map[string]*types.Backend{}
+	}
+	return configurationCopy, nil
+}
+
+// setWebETag sets the ETag of the configuration of the web provider, to send back in the If-Match
+// header of the next update.
+func (provider *WebProvider) setWebETag(response http.ResponseWriter, providerName string) {
+	if providerName != "web" {
+		return
+	}
+	if _, etag, err := provider.webConfiguration(); err == nil {
+		response.Header().Set("ETag", etag)
+	}
+}
+
+// updateWebConfiguration applies update to the configuration of the web provider, sends the result
+// once validated, and responds once it is applied.
+func (provider *WebProvider) updateWebConfiguration(response http.ResponseWriter, request *http.Request, update webConfigurationUpdate) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(response, "REST API is in read-only mode")
+		return
+	}
+	result, pending := provider.sendWebConfiguration(response, request, update)
+	if pending == nil {
+		return
+	}
+	select {
+	case err := <-pending.loaded:
+		if err == errWebConfigurationReplaced {
+			http.Error(response, "The web provider configuration was "+err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(response, "The web provider configuration was rejected: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	case <-time.After(provider.server.globalConfiguration.ProvidersThrottleDuration + webConfigurationTimeout):
+		response.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(response, "The web provider configuration is not applied yet")
+		return
+	}
+
+	provider.setWebETag(response, "web")
+	if resultFunc, ok := result.(func() interface{}); ok {
+		result = resultFunc()
+	}
+	if result == nil {
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, result)
+}
+
+// sendWebConfiguration applies update to the configuration of the web provider the next update applies
+// to, if the request matches its ETag, and sends the result once validated. It responds and returns a nil
+// pending configuration if it cannot. The updates are sent one at a time, each applying to the one sent
+// before, so that none is lost.
+func (provider *WebProvider) sendWebConfiguration(response http.ResponseWriter, request *http.Request, update webConfigurationUpdate) (interface{}, *pendingWebConfiguration) {
+	provider.updateMutex.Lock()
+	defer provider.updateMutex.Unlock()
+	configuration, etag, err := provider.nextWebConfiguration()
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return nil, nil
+	}
+	if ifMatch := request.Header.Get("If-Match"); len(ifMatch) > 0 && ifMatch != "*" && ifMatch != etag {
+		response.Header().Set("ETag", etag)
+		http.Error(response, "The web provider configuration changed since "+ifMatch, http.StatusPreconditionFailed)
+		return nil, nil
+	}
+	result, status, err := update(configuration)
+	if err != nil {
+		http.Error(response, err.Error(), status)
+		return nil, nil
+	}
+	if err := provider.validateConfiguration(configuration); err != nil {
+		http.Error(response, "Invalid web provider configuration: "+err.Error(), http.StatusBadRequest)
+		return nil, nil
+	}
+	sent, err := copyConfiguration(configuration)
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return nil, nil
+	}
+
+	pending := &pendingWebConfiguration{configuration: configuration, sent: sent, loaded: make(chan error, 1)}
+	provider.configurationMutex.Lock()
+	provider.pending = append(provider.pending, pending)
+	provider.configurationMutex.Unlock()
+	provider.configurationChan <- types.ConfigMessage{ProviderName: "web", Configuration: configuration}
+	return result, pending
+}
+
+// configurationLoaded tells the update waiting for configuration whether it was applied. The updates
+// sent before, replaced by configuration while they were throttled, are applied with it since it
+// includes them, or not at all if it is rejected.
+func (provider *WebProvider) configurationLoaded(configuration *types.Configuration, err error) {
+	provider.configurationMutex.Lock()
+	defer provider.configurationMutex.Unlock()
+	for i, pending := range provider.pending {
+		if pending.configuration != configuration {
+			continue
+		}
+		for _, replaced := range provider.pending[:i] {
+			if err != nil {
+				replaced.loaded <- errWebConfigurationReplaced
+			} else {
+				replaced.loaded <- nil
+			}
+		}
+		pending.loaded <- err
+		provider.pending = append([]*pendingWebConfiguration(nil), provider.pending[i+1:]...)
+		return
+	}
+}
+
+// validateConfiguration checks that the frontends of configuration are reachable and routed to
+// its backends, and that the servers of the backends have valid URLs.
+func (provider *WebProvider) validateConfiguration(configuration *types.Configuration) error {
+	globalConfiguration := provider.server.globalConfiguration
+	for frontendName, frontend := range configuration.Frontends {
+		if frontend == nil {
+			return fmt.Errorf("empty frontend %s", frontendName)
+		}
+		entryPoints := frontend.EntryPoints
+		if len(entryPoints) == 0 {
+			entryPoints = globalConfiguration.DefaultEntryPoints
+		}
+		if len(entryPoints) == 0 {
+			return fmt.Errorf("no entry point for frontend %s", frontendName)
+		}
+		for _, entryPointName := range entryPoints {
+			if _, ok := globalConfiguration.EntryPoints[entryPointName]; !ok {
+				return fmt.Errorf("undefined entry point %s in frontend %s", entryPointName, frontendName)
+			}
+		}
+		for routeName, route := range frontend.Routes {
+			if err := getRoute(&serverRoute{route: mux.NewRouter().NewRoute()}, &route); err != nil {
+				return fmt.Errorf("invalid route %s of frontend %s: %s", routeName, frontendName, err)
+			}
+		}
+		for _, backendName := range frontendBackendNames(frontend) {
+			if configuration.Backends[backendName] == nil {
+				return fmt.Errorf("undefined backend %s in frontend %s", backendName, frontendName)
+			}
+		}
+	}
+	for backendName, backend := range configuration.Backends {
+		if backend == nil {
+			return fmt.Errorf("empty backend %s", backendName)
+		}
+		if backend.LoadBalancer != nil {
+			if _, err := types.NewLoadBalancerMethod(backend.LoadBalancer); err != nil {
+				return fmt.Errorf("invalid load balancer method %s of backend %s", backend.LoadBalancer.Method, backendName)
+			}
+		}
+		for serverName, server := range backend.Servers {
+			serverURL, err := url.Parse(server.URL)
+			if err != nil {
+				return fmt.Errorf("invalid URL of server %s of backend %s: %s", serverName, backendName, err)
+			}
+			if len(serverURL.Scheme) == 0 || len(serverURL.Host) == 0 {
+				return fmt.Errorf("invalid URL of server %s of backend %s: %s", serverName, backendName, server.URL)
+			}
+		}
+	}
+	return nil
+}
+
+// readBody decodes the JSON body of request into v.
+func readBody(request *http.Request, v interface{}) (int, error) {
+	body, _ := ioutil.ReadAll(request.Body)
+	if err := json.Unmarshal(body, v); err != nil {
+		log.Errorf("Error parsing %s %+v", request.URL.Path, err)
+		return http.StatusBadRequest, err
+	}
+	return http.StatusOK, nil
+}
+
+var errNotFound = errors.New(http.StatusText(http.StatusNotFound))
+
+func (provider *WebProvider) putWebFrontendHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		frontend := &types.Frontend{}
+		if status, err := readBody(request, frontend); err != nil {
+			return nil, status, err
+		}
+		configuration.Frontends[mux.Vars(request)["frontend"]] = frontend
+		return frontend, http.StatusOK, nil
+	})
+}
+
+func (provider *WebProvider) deleteWebFrontendHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		frontendName := mux.Vars(request)["frontend"]
+		if _, ok := configuration.Frontends[frontendName]; !ok {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		delete(configuration.Frontends, frontendName)
+		return nil, http.StatusNoContent, nil
+	})
+}
+
+func (provider *WebProvider) putWebBackendHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		backend := &types.Backend{}
+		if status, err := readBody(request, backend); err != nil {
+			return nil, status, err
+		}
+		configuration.Backends[mux.Vars(request)["backend"]] = backend
+		return backend, http.StatusOK, nil
+	})
+}
+
+func (provider *WebProvider) deleteWebBackendHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		backendName := mux.Vars(request)["backend"]
+		if _, ok := configuration.Backends[backendName]; !ok {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		delete(configuration.Backends, backendName)
+		return nil, http.StatusNoContent, nil
+	})
+}
+
+func (provider *WebProvider) putWebServerHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		vars := mux.Vars(request)
+		backend, ok := configuration.Backends[vars["backend"]]
+		if !ok {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		server := types.Server{}
+		if status, err := readBody(request, &server); err != nil {
+			return nil, status, err
+		}
+		if backend.Servers == nil {
+			backend.Servers = map[string]types.Server{}
+		}
+		backend.Servers[vars["server"]] = server
+		return server, http.StatusOK, nil
+	})
+}
+
+func (provider *WebProvider) deleteWebServerHandler(response http.ResponseWriter, request *http.Request) {
+	provider.updateWebConfiguration(response, request, func(configuration *types.Configuration) (interface{}, int, error) {
+		vars := mux.Vars(request)
+		backend, ok := configuration.Backends[vars["backend"]]
+		if !ok {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		if _, ok := backend.Servers[vars["server"]]; !ok {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		delete(backend.Servers, vars["server"])
+		return nil, http.StatusNoContent, nil
+	})
+}